@@ -0,0 +1,132 @@
+package gathering
+
+import (
+	"time"
+)
+
+// The kinds of ArenaAction
+const (
+	ActionCast     = "cast"
+	ActionPlay     = "play"
+	ActionActivate = "activate"
+	ActionAttack   = "attack"
+	ActionBlock    = "block"
+	ActionTarget   = "target"
+	ActionConcede  = "concede"
+)
+
+// actionKinds are the PerformActionResp action types we log, passing
+// priority and the like are left out
+var actionKinds = map[string]string{
+	"ActionType_Cast":          ActionCast,
+	"ActionType_Play":          ActionPlay,
+	"ActionType_PlayMDFC":      ActionPlay,
+	"ActionType_Activate":      ActionActivate,
+	"ActionType_Activate_Mana": ActionActivate,
+}
+
+// ArenaActionCard is a game object used in an action. GrpID is the card,
+// found from the game state when the message only has the instance.
+type ArenaActionCard struct {
+	InstanceID int `json:"instanceId"`
+	GrpID      int `json:"grpId"`
+}
+
+// ArenaAction is something the player did in a game. Card is what they cast,
+// played, activated, attacked or blocked with. Targets are what a block or
+// target selection was aimed at.
+type ArenaAction struct {
+	Kind        string            `json:"kind"`
+	GameStateID int               `json:"gameStateId"`
+	Turn        int               `json:"turn"`
+	Time        *time.Time        `json:"time"`
+	Card        *ArenaActionCard  `json:"card"`
+	Targets     []ArenaActionCard `json:"targets"`
+}
+
+// LogClientMessage adds what the player did to the current game. A deck
+// submitted after sideboarding goes to the next game.
+func (a *ArenaMatch) LogClientMessage(msg *ArenaClientMessage, at *time.Time) {
+	p := msg.Payload
+	if p == nil {
+		return
+	}
+	if p.Type == ClientSubmitDeckResp && p.SubmitDeckResp != nil {
+		a.SubmitDeck(p.SubmitDeckResp.Deck.ArenaDeck())
+		return
+	}
+	if len(a.Games) == 0 {
+		return
+	}
+	if t := parseEpoch([]byte(msg.Timestamp)); t != nil {
+		at = t
+	}
+	a.Games[a.currentGame].recordActions(p, at)
+}
+
+// recordActions adds the actions in a message to the game's action log
+func (g *ArenaGame) recordActions(msg *ClientToGREMessage, at *time.Time) {
+	state := g.State().At(msg.GameStateID)
+	if state == nil {
+		state = g.State().Current()
+	}
+	card := func(instanceID, grpID int) ArenaActionCard {
+		if o, ok := state.Objects[instanceID]; ok && grpID == 0 {
+			grpID = o.GrpID
+		}
+		return ArenaActionCard{InstanceID: instanceID, GrpID: grpID}
+	}
+	add := func(kind string, c *ArenaActionCard, targets []ArenaActionCard) {
+		g.Actions = append(g.Actions, &ArenaAction{
+			Kind:        kind,
+			GameStateID: msg.GameStateID,
+			Turn:        state.Turn(),
+			Time:        at,
+			Card:        c,
+			Targets:     targets,
+		})
+	}
+	switch msg.Type {
+	case ClientPerformActionResp:
+		if msg.PerformActionResp == nil {
+			return
+		}
+		for _, action := range msg.PerformActionResp.Actions {
+			if kind, ok := actionKinds[action.ActionType]; ok {
+				c := card(action.InstanceID, action.GrpID)
+				add(kind, &c, nil)
+			}
+		}
+	case ClientDeclareAttackersResp:
+		if msg.DeclareAttackersResp == nil {
+			return
+		}
+		for _, attacker := range msg.DeclareAttackersResp.SelectedAttackers {
+			c := card(attacker.AttackerInstanceID, 0)
+			add(ActionAttack, &c, nil)
+		}
+	case ClientDeclareBlockersResp:
+		if msg.DeclareBlockersResp == nil {
+			return
+		}
+		for _, blocker := range msg.DeclareBlockersResp.SelectedBlockers {
+			c := card(blocker.BlockerInstanceID, 0)
+			var attackers []ArenaActionCard
+			for _, id := range blocker.SelectedAttackerInstanceIDs {
+				attackers = append(attackers, card(id, 0))
+			}
+			add(ActionBlock, &c, attackers)
+		}
+	case ClientSelectTargetsResp:
+		if msg.SelectTargetsResp == nil {
+			return
+		}
+		var targets []ArenaActionCard
+		for _, t := range msg.SelectTargetsResp.Target.Targets {
+			targets = append(targets, card(t.TargetInstanceID, 0))
+		}
+		add(ActionTarget, nil, targets)
+	case ClientConcedeReq:
+		add(ActionConcede, nil, nil)
+	}
+}
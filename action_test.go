@@ -0,0 +1,64 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testClientMessage(t *testing.T, payload string) *ArenaClientMessage {
+	s := &Segment{Text: []byte(`{"requestId": 1, "timestamp": "1554231701005",
+ "clientToMatchServiceMessageType": "ClientToMatchServiceMessageType_ClientToGREMessage",
+ "payload": ` + payload + `}`)}
+	msg, err := s.ParseClientToGRE()
+	assert.Nil(t, err)
+	return msg
+}
+
+func TestGameActions(t *testing.T) {
+	a := assert.New(t)
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(`[
+{"type": "GREMessageType_GameStateMessage", "gameStateMessage": {"type": "GameStateType_Full", "gameStateId": 1,
+ "turnInfo": {"turnNumber": 3},
+ "gameObjects": [
+  {"instanceId": 160, "grpId": 68739, "type": "GameObjectType_Card"},
+  {"instanceId": 170, "grpId": 68800, "type": "GameObjectType_Card"},
+  {"instanceId": 171, "grpId": 68801, "type": "GameObjectType_Card"}
+ ]}}]`), &event.GreToClientEvent.GreToClientMessages))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&event)
+
+	for _, payload := range []string{
+		`{"type": "ClientMessageType_PerformActionResp", "gameStateId": 1, "performActionResp": {"actions": [
+			{"actionType": "ActionType_Cast", "grpId": 68739, "instanceId": 160}]}}`,
+		`{"type": "ClientMessageType_PerformActionResp", "gameStateId": 1, "performActionResp": {"actions": [
+			{"actionType": "ActionType_Pass"}]}}`,
+		`{"type": "ClientMessageType_SelectTargetsResp", "gameStateId": 1, "selectTargetsResp": {"target": {
+			"targetIdx": 1, "targets": [{"targetInstanceId": 170}]}}}`,
+		`{"type": "ClientMessageType_DeclareAttackersResp", "gameStateId": 1, "declareAttackersResp": {
+			"selectedAttackers": [{"attackerInstanceId": 160}]}}`,
+		`{"type": "ClientMessageType_DeclareBlockersResp", "gameStateId": 1, "declareBlockersResp": {
+			"selectedBlockers": [{"blockerInstanceId": 171, "selectedAttackerInstanceIds": [170]}]}}`,
+		`{"type": "ClientMessageType_ConcedeReq", "concedeReq": {"scope": "MatchScope_Game"}}`,
+	} {
+		match.LogClientMessage(testClientMessage(t, payload), nil)
+	}
+
+	actions := match.Games[0].Actions
+	a.Len(actions, 5)
+	a.Equal(ActionCast, actions[0].Kind)
+	a.Equal(&ArenaActionCard{InstanceID: 160, GrpID: 68739}, actions[0].Card)
+	a.Equal(3, actions[0].Turn)
+	a.Equal(int64(1554231701005), actions[0].Time.UnixNano()/1e6)
+	a.Equal(ActionTarget, actions[1].Kind)
+	a.Nil(actions[1].Card)
+	a.Equal([]ArenaActionCard{{InstanceID: 170, GrpID: 68800}}, actions[1].Targets)
+	a.Equal(ActionAttack, actions[2].Kind)
+	a.Equal(68739, actions[2].Card.GrpID)
+	a.Equal(ActionBlock, actions[3].Kind)
+	a.Equal(68801, actions[3].Card.GrpID)
+	a.Equal([]ArenaActionCard{{InstanceID: 170, GrpID: 68800}}, actions[3].Targets)
+	a.Equal(ActionConcede, actions[4].Kind)
+}
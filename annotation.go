@@ -0,0 +1,194 @@
+package gathering
+
+// The annotation types we decode
+const (
+	AnnotationZoneTransfer    = "AnnotationType_ZoneTransfer"
+	AnnotationDamageDealt     = "AnnotationType_DamageDealt"
+	AnnotationModifiedLife    = "AnnotationType_ModifiedLife"
+	AnnotationObjectIDChanged = "AnnotationType_ObjectIdChanged"
+)
+
+// Annotation is something that happened between two game states, as the GRE
+// sends it. What it means depends on its types and the key/value details.
+type Annotation struct {
+	ID          int                `json:"id"`
+	AffectorID  int                `json:"affectorId"`
+	AffectedIDs []int              `json:"affectedIds"`
+	Type        []string           `json:"type"`
+	Details     []AnnotationDetail `json:"details"`
+}
+
+// AnnotationDetail is a key and its values
+type AnnotationDetail struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type"`
+	ValueInt32  []int    `json:"valueInt32"`
+	ValueString []string `json:"valueString"`
+}
+
+// Is checks if the annotation has the type
+func (a *Annotation) Is(t string) bool {
+	for _, at := range a.Type {
+		if at == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Int is the first number with the key, or 0
+func (a *Annotation) Int(key string) int {
+	for _, d := range a.Details {
+		if d.Key == key && len(d.ValueInt32) > 0 {
+			return d.ValueInt32[0]
+		}
+	}
+	return 0
+}
+
+// String is the first string with the key, or ""
+func (a *Annotation) String(key string) string {
+	for _, d := range a.Details {
+		if d.Key == key && len(d.ValueString) > 0 {
+			return d.ValueString[0]
+		}
+	}
+	return ""
+}
+
+// GameEvent is an annotation decoded into what it means. Only the field for
+// its Type is set. Ids are instance ids, with the grpId of the card next to
+// them when the game state knows it.
+type GameEvent struct {
+	Type            string           `json:"type"`
+	GameStateID     int              `json:"gameStateId"`
+	ZoneTransfer    *ZoneTransfer    `json:"zoneTransfer,omitempty"`
+	DamageDealt     *DamageDealt     `json:"damageDealt,omitempty"`
+	ModifiedLife    *ModifiedLife    `json:"modifiedLife,omitempty"`
+	ObjectIDChanged *ObjectIDChanged `json:"objectIdChanged,omitempty"`
+}
+
+// ZoneTransfer is an object moving between zones. Category is why, like
+// CastSpell, Draw, Discard, Destroy or SBA_Damage. AffectorID is what made
+// it move, when there was something.
+type ZoneTransfer struct {
+	InstanceID    int    `json:"instanceId"`
+	GrpID         int    `json:"grpId"`
+	ZoneSrc       int    `json:"zoneSrc"`
+	ZoneDest      int    `json:"zoneDest"`
+	ZoneSrcType   string `json:"zoneSrcType"`
+	ZoneDestType  string `json:"zoneDestType"`
+	Category      string `json:"category"`
+	AffectorID    int    `json:"affectorId"`
+	AffectorGrpID int    `json:"affectorGrpId"`
+}
+
+// DamageDealt is damage from a source to an object, or to the player in
+// TargetSeatID
+type DamageDealt struct {
+	SourceID     int `json:"sourceId"`
+	SourceGrpID  int `json:"sourceGrpId"`
+	TargetID     int `json:"targetId"`
+	TargetGrpID  int `json:"targetGrpId"`
+	TargetSeatID int `json:"targetSeatId"`
+	Damage       int `json:"damage"`
+	DamageType   int `json:"damageType"`
+}
+
+// ModifiedLife is a player's life total changing by Change
+type ModifiedLife struct {
+	SeatID      int `json:"seatId"`
+	Change      int `json:"change"`
+	SourceID    int `json:"sourceId"`
+	SourceGrpID int `json:"sourceGrpId"`
+}
+
+// ObjectIDChanged is an object getting a new instance id, which happens
+// whenever it changes zones
+type ObjectIDChanged struct {
+	OrigID int `json:"origId"`
+	NewID  int `json:"newId"`
+	GrpID  int `json:"grpId"`
+}
+
+// grpIDOf finds the card of an instance in either state
+func grpIDOf(id int, states ...*GameSnapshot) int {
+	for _, s := range states {
+		if o, ok := s.Objects[id]; ok {
+			return o.GrpID
+		}
+	}
+	return 0
+}
+
+func zoneType(id int, states ...*GameSnapshot) string {
+	for _, s := range states {
+		if z, ok := s.Zones[id]; ok {
+			return z.Type
+		}
+	}
+	return ""
+}
+
+// decodeAnnotations turns the annotations sent with next into events. prev is
+// the state before, for objects that are gone from next.
+func decodeAnnotations(annotations []Annotation, prev, next *GameSnapshot) []*GameEvent {
+	var events []*GameEvent
+	for i := range annotations {
+		a := &annotations[i]
+		affected := 0
+		if len(a.AffectedIDs) > 0 {
+			affected = a.AffectedIDs[0]
+		}
+		event := &GameEvent{GameStateID: next.ID}
+		switch {
+		case a.Is(AnnotationZoneTransfer):
+			event.Type = AnnotationZoneTransfer
+			event.ZoneTransfer = &ZoneTransfer{
+				InstanceID:    affected,
+				GrpID:         grpIDOf(affected, next, prev),
+				ZoneSrc:       a.Int("zone_src"),
+				ZoneDest:      a.Int("zone_dest"),
+				ZoneSrcType:   zoneType(a.Int("zone_src"), next, prev),
+				ZoneDestType:  zoneType(a.Int("zone_dest"), next, prev),
+				Category:      a.String("category"),
+				AffectorID:    a.AffectorID,
+				AffectorGrpID: grpIDOf(a.AffectorID, next, prev),
+			}
+		case a.Is(AnnotationDamageDealt):
+			event.Type = AnnotationDamageDealt
+			damage := &DamageDealt{
+				SourceID:    a.AffectorID,
+				SourceGrpID: grpIDOf(a.AffectorID, next, prev),
+				TargetID:    affected,
+				TargetGrpID: grpIDOf(affected, next, prev),
+				Damage:      a.Int("damage"),
+				DamageType:  a.Int("type"),
+			}
+			if _, ok := next.Players[affected]; ok && damage.TargetGrpID == 0 {
+				damage.TargetSeatID = affected
+			}
+			event.DamageDealt = damage
+		case a.Is(AnnotationModifiedLife):
+			event.Type = AnnotationModifiedLife
+			event.ModifiedLife = &ModifiedLife{
+				SeatID:      affected,
+				Change:      a.Int("life"),
+				SourceID:    a.AffectorID,
+				SourceGrpID: grpIDOf(a.AffectorID, next, prev),
+			}
+		case a.Is(AnnotationObjectIDChanged):
+			event.Type = AnnotationObjectIDChanged
+			newID := a.Int("new_id")
+			event.ObjectIDChanged = &ObjectIDChanged{
+				OrigID: a.Int("orig_id"),
+				NewID:  newID,
+				GrpID:  grpIDOf(newID, next, prev),
+			}
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
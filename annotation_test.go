@@ -0,0 +1,102 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAnnotationEvent = `{"greToClientEvent": {"greToClientMessages": [
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+  "type": "GameStateType_Full", "gameStateId": 1,
+  "turnInfo": {"turnNumber": 4, "activePlayer": 1},
+  "players": [{"lifeTotal": 20, "systemSeatNumber": 1}, {"lifeTotal": 20, "systemSeatNumber": 2}],
+  "zones": [
+   {"zoneId": 27, "type": "ZoneType_Stack"},
+   {"zoneId": 28, "type": "ZoneType_Battlefield", "objectInstanceIds": [170]},
+   {"zoneId": 31, "type": "ZoneType_Hand", "ownerSeatId": 1, "objectInstanceIds": [160]},
+   {"zoneId": 37, "type": "ZoneType_Graveyard", "ownerSeatId": 2}
+  ],
+  "gameObjects": [
+   {"instanceId": 160, "grpId": 68739, "type": "GameObjectType_Card", "zoneId": 31},
+   {"instanceId": 170, "grpId": 68800, "type": "GameObjectType_Card", "zoneId": 28}
+  ]}},
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+  "type": "GameStateType_Diff", "gameStateId": 2, "prevGameStateId": 1,
+  "zones": [
+   {"zoneId": 27, "type": "ZoneType_Stack", "objectInstanceIds": [161]},
+   {"zoneId": 31, "type": "ZoneType_Hand", "ownerSeatId": 1}
+  ],
+  "gameObjects": [{"instanceId": 161, "grpId": 68739, "type": "GameObjectType_Card", "zoneId": 27}],
+  "diffDeletedInstanceIds": [160],
+  "annotations": [
+   {"id": 1, "affectorId": 1, "affectedIds": [160], "type": ["AnnotationType_ObjectIdChanged"], "details": [
+    {"key": "orig_id", "type": "KeyValuePairValueType_int32", "valueInt32": [160]},
+    {"key": "new_id", "type": "KeyValuePairValueType_int32", "valueInt32": [161]}]},
+   {"id": 2, "affectorId": 1, "affectedIds": [161], "type": ["AnnotationType_ZoneTransfer"], "details": [
+    {"key": "zone_src", "type": "KeyValuePairValueType_int32", "valueInt32": [31]},
+    {"key": "zone_dest", "type": "KeyValuePairValueType_int32", "valueInt32": [27]},
+    {"key": "category", "type": "KeyValuePairValueType_string", "valueString": ["CastSpell"]}]},
+   {"id": 3, "affectedIds": [161], "type": ["AnnotationType_TappedUntappedPermanent"]}
+  ]}},
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+  "type": "GameStateType_Diff", "gameStateId": 3, "prevGameStateId": 2,
+  "players": [{"lifeTotal": 17, "systemSeatNumber": 2}],
+  "zones": [
+   {"zoneId": 28, "type": "ZoneType_Battlefield"},
+   {"zoneId": 37, "type": "ZoneType_Graveyard", "ownerSeatId": 2, "objectInstanceIds": [171]}
+  ],
+  "gameObjects": [{"instanceId": 171, "grpId": 68800, "type": "GameObjectType_Card", "zoneId": 37}],
+  "diffDeletedInstanceIds": [170],
+  "annotations": [
+   {"id": 4, "affectorId": 161, "affectedIds": [170], "type": ["AnnotationType_DamageDealt"], "details": [
+    {"key": "damage", "type": "KeyValuePairValueType_int32", "valueInt32": [3]}]},
+   {"id": 5, "affectorId": 161, "affectedIds": [2], "type": ["AnnotationType_DamageDealt"], "details": [
+    {"key": "damage", "type": "KeyValuePairValueType_int32", "valueInt32": [3]}]},
+   {"id": 6, "affectorId": 161, "affectedIds": [2], "type": ["AnnotationType_ModifiedLife"], "details": [
+    {"key": "life", "type": "KeyValuePairValueType_int32", "valueInt32": [-3]}]},
+   {"id": 7, "affectedIds": [171], "type": ["AnnotationType_ZoneTransfer"], "details": [
+    {"key": "zone_src", "type": "KeyValuePairValueType_int32", "valueInt32": [28]},
+    {"key": "zone_dest", "type": "KeyValuePairValueType_int32", "valueInt32": [37]},
+    {"key": "category", "type": "KeyValuePairValueType_string", "valueString": ["SBA_Damage"]}]}
+  ]}}
+]}}`
+
+func TestGameAnnotations(t *testing.T) {
+	a := assert.New(t)
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(testAnnotationEvent), &event))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&event)
+	turns := match.Games[0].Turns
+	a.Len(turns, 1)
+	events := turns[0].Events
+	a.Len(events, 6)
+
+	a.Equal(AnnotationObjectIDChanged, events[0].Type)
+	a.Equal(&ObjectIDChanged{OrigID: 160, NewID: 161, GrpID: 68739}, events[0].ObjectIDChanged)
+
+	cast := events[1].ZoneTransfer
+	a.Equal(2, events[1].GameStateID)
+	a.Equal("CastSpell", cast.Category)
+	a.Equal(68739, cast.GrpID)
+	a.Equal("ZoneType_Hand", cast.ZoneSrcType)
+	a.Equal("ZoneType_Stack", cast.ZoneDestType)
+
+	creature := events[2].DamageDealt
+	a.Equal(68739, creature.SourceGrpID)
+	a.Equal(68800, creature.TargetGrpID)
+	a.Equal(0, creature.TargetSeatID)
+	a.Equal(3, creature.Damage)
+	player := events[3].DamageDealt
+	a.Equal(2, player.TargetSeatID)
+
+	a.Equal(&ModifiedLife{SeatID: 2, Change: -3, SourceID: 161, SourceGrpID: 68739}, events[4].ModifiedLife)
+
+	died := events[5].ZoneTransfer
+	a.Equal("SBA_Damage", died.Category)
+	a.Equal(68800, died.GrpID)
+	a.Equal("ZoneType_Battlefield", died.ZoneSrcType)
+	a.Equal("ZoneType_Graveyard", died.ZoneDestType)
+}
@@ -0,0 +1,116 @@
+package gathering
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 4 Shock (M19) 156
+// 4 Shock
+var importLineRegex = regexp.MustCompile(`^(\d+)\s+(.+?)(?:\s+\(([A-Za-z0-9_]+)\)(?:\s+\S+)?)?$`)
+
+// Archetype is a reference decklist. Cards are counted by lower case name,
+// main deck and sideboard together, since either can show up after
+// sideboarding.
+type Archetype struct {
+	Name  string
+	Cards map[string]int
+}
+
+// ReadArchetype reads a decklist in the Arena import format. The section
+// headers (Deck, Sideboard, ...) and blank lines are skipped.
+func ReadArchetype(name string, r io.Reader) (*Archetype, error) {
+	a := &Archetype{
+		Name:  name,
+		Cards: make(map[string]int),
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := importLineRegex.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		count, _ := strconv.Atoi(m[1])
+		a.Cards[strings.ToLower(m[2])] += count
+	}
+	return a, scanner.Err()
+}
+
+// LoadArchetypes reads every .txt decklist in a directory. The file name,
+// without the extension, is the archetype name.
+func LoadArchetypes(dir string) ([]*Archetype, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var archetypes []*Archetype
+	for _, info := range files {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".txt" {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		a, err := ReadArchetype(strings.TrimSuffix(info.Name(), ".txt"), f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		archetypes = append(archetypes, a)
+	}
+	return archetypes, nil
+}
+
+// ArchetypeScore is how well a deck matches an archetype. Score is the share
+// of the cards seen, counting copies, that are in the reference list.
+type ArchetypeScore struct {
+	Archetype string  `json:"archetype"`
+	Score     float64 `json:"score"`
+	Matched   int     `json:"matched"`
+}
+
+// ClassifyOpponent scores the opponent's deck against each archetype, best
+// match first. Cards the database doesn't know are left out.
+func ClassifyOpponent(deck *OpponentDeck, archetypes []*Archetype, db CardDatabase) []ArchetypeScore {
+	seen := make(map[string]int)
+	total := 0
+	for _, c := range deck.Cards {
+		card, ok := db.Card(c.GrpID)
+		if !ok {
+			continue
+		}
+		seen[strings.ToLower(card.Name)] += c.MinCopies
+		total += c.MinCopies
+	}
+	scores := []ArchetypeScore{}
+	for _, a := range archetypes {
+		matched := 0
+		for name, n := range seen {
+			if ref := a.Cards[name]; ref < n {
+				matched += ref
+			} else {
+				matched += n
+			}
+		}
+		score := ArchetypeScore{Archetype: a.Name, Matched: matched}
+		if total > 0 {
+			score.Score = float64(matched) / float64(total)
+		}
+		scores = append(scores, score)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Archetype < scores[j].Archetype
+	})
+	return scores
+}
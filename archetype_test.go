@@ -0,0 +1,65 @@
+package gathering
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMonoRed = `Deck
+4 Shock (M19) 156
+4 Lightning Strike (XLN) 149
+20 Mountain (XLN) 272
+
+Sideboard
+2 Fling (M19) 139
+`
+
+func TestReadArchetype(t *testing.T) {
+	a := assert.New(t)
+	arch, err := ReadArchetype("Mono Red", bytes.NewBufferString(testMonoRed))
+	a.Nil(err)
+	a.Equal("Mono Red", arch.Name)
+	a.Equal(map[string]int{
+		"shock":            4,
+		"lightning strike": 4,
+		"mountain":         20,
+		"fling":            2,
+	}, arch.Cards)
+}
+
+func TestClassifyOpponent(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "archetypes")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	a.Nil(ioutil.WriteFile(filepath.Join(dir, "Mono Red.txt"), []byte(testMonoRed), 0644))
+	a.Nil(ioutil.WriteFile(filepath.Join(dir, "Simic.txt"), []byte("4 Growth Spiral (RNA) 178\n4 Shock (M19) 156\n"), 0644))
+	a.Nil(ioutil.WriteFile(filepath.Join(dir, "notes.md"), []byte("4 Shock\n"), 0644))
+	archetypes, err := LoadArchetypes(dir)
+	a.Nil(err)
+	a.Len(archetypes, 2)
+
+	db, err := ReadCardMap(bytes.NewBufferString(`[
+		{"grpId": 1, "name": "Shock"},
+		{"grpId": 2, "name": "Fling"},
+		{"grpId": 3, "name": "Growth Spiral"}
+	]`))
+	a.Nil(err)
+	deck := &OpponentDeck{Cards: []OpponentCard{
+		{GrpID: 1, MinCopies: 2},
+		{GrpID: 2, MinCopies: 1},
+		{GrpID: 99, MinCopies: 4},
+	}}
+	scores := ClassifyOpponent(deck, archetypes, db)
+	a.Len(scores, 2)
+	a.Equal("Mono Red", scores[0].Archetype)
+	a.Equal(1.0, scores[0].Score)
+	a.Equal(3, scores[0].Matched)
+	a.Equal("Simic", scores[1].Archetype)
+	a.InDelta(2.0/3.0, scores[1].Score, 0.001)
+}
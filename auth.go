@@ -11,14 +11,16 @@ type ArenaAuthRequest struct {
 	Payload ArenaAuthRequestPayload `json:"Payload"`
 }
 
-// IsPlayerAuth checks if a segment contains an auth statement
+// IsPlayerAuth checks if a segment contains an auth statement. Match
+// payloads list the screen names of both players, so only segments that
+// aren't anything more important count.
 func (s *Segment) IsPlayerAuth() bool {
 	return s.SegmentType == PlayerAuth
 }
 
 // ParseAuth returns the players username
 func (s *Segment) ParseAuth() ([]byte, error) {
-	re := segmentTypeChecks[PlayerAuth].Copy()
+	re := screenNameRegex.Copy()
 	matches := re.FindSubmatch(s.Text)
 	if len(matches) == 2 {
 		return matches[1], nil
@@ -1 +1,22 @@
 package gathering
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthSkipsMatchPlayers(t *testing.T) {
+	a := assert.New(t)
+	l := &bo3Log{}
+	l.segment(`{"greToClientEvent": {"greToClientMessages": [{"type": "GREMessageType_ConnectResp",
+ "players": [{"screenName": "Opponent", "systemSeatNumber": 2}]}]}}`)
+	l.segment(`{"screenName": "Player"}`)
+	p := NewLogParser(nil)
+	_, err := p.Parse(l)
+	a.Nil(err)
+	p.Flush()
+	name, err := p.Log().Auth()
+	a.Nil(err)
+	a.Equal("Player", string(name))
+}
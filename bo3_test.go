@@ -0,0 +1,126 @@
+package gathering
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bo3Log builds a best of three log. Game two is started after sideboarding
+// only when sideboard is set.
+type bo3Log struct {
+	bytes.Buffer
+	line int
+}
+
+func (l *bo3Log) segment(body string) {
+	l.line++
+	fmt.Fprintf(&l.Buffer, "[UnityCrossThreadLogger]4/2/2019 3:%02d:00 PM\n%s\n", l.line, body)
+}
+
+func (l *bo3Log) gre(game, id int, state string, results string) {
+	l.segment(fmt.Sprintf(`{"timestamp": "%d", "greToClientEvent": {"greToClientMessages": [
+{"type": "GREMessageType_GameStateMessage", "gameStateMessage": {"type": "GameStateType_Full", "gameStateId": %d,
+ "gameInfo": {"gameNumber": %d, "matchState": "%s", "results": [%s]},
+ "gameObjects": [{"instanceId": 1, "grpId": %d, "type": "GameObjectType_Card", "ownerSeatId": 1}]}}]}}`,
+		1554231600000+int64(l.line)*60000, id, game, state, results, 60000+game))
+}
+
+func (l *bo3Log) gameStop(game, winner int) {
+	l.segment(fmt.Sprintf(`==> Log.Info(%d):
+{"params": {"messageName": "DuelScene.GameStop", "payloadObject": {
+ "seatId": 1, "teamId": 1, "gameNumber": %d, "matchId": "m1", "startingTeamId": 1,
+ "winningTeamId": %d, "winningReason": "ResultReason_Game"}}}`, l.line, game, winner))
+}
+
+func newBo3Log(sideboard bool) *bo3Log {
+	l := &bo3Log{}
+	l.segment(`<== Event.DeckSubmitV3(1)
+{"CourseDeck": {"id": "deck1", "mainDeck": [{"id": 60001, "quantity": 4}]}}`)
+	l.segment(` (Incoming Event.MatchCreated)
+{"matchId": "m1", "opponentScreenName": "Opponent"}`)
+	l.gre(1, 1, "MatchState_GameInProgress", "")
+	l.gameStop(1, 2)
+	if sideboard {
+		l.segment(`==> Log.Info(9):
+{"params": {"messageName": "DuelScene.SideboardingStart"}}`)
+		l.segment(`{"requestId": 3, "clientToMatchServiceMessageType": "ClientToMatchServiceMessageType_ClientToGREMessage",
+ "payload": {"type": "ClientMessageType_SubmitDeckResp", "submitDeckResp": {"deck": {
+  "deckCards": [60001, 60001, 60001, 70001], "sideboardCards": [60001]}}}}`)
+		l.segment(`==> Log.Info(10):
+{"params": {"messageName": "DuelScene.SideboardingStop"}}`)
+	}
+	l.gre(2, 10, "MatchState_GameInProgress", "")
+	l.gameStop(2, 1)
+	l.gre(3, 20, "MatchState_GameInProgress", "")
+	// Game three ends with the match, without a GameStop
+	l.gre(3, 21, "MatchState_MatchComplete", `
+{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 2},
+{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1},
+{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1, "reason": "ResultReason_Concede"}`)
+	l.segment(`{"matchGameRoomStateChangedEvent": {"gameRoomInfo": {"stateType": "MatchGameRoomStateType_MatchCompleted",
+ "finalMatchResult": {"matchId": "m1", "matchCompletedReason": "MatchCompletedReasonType_Success", "resultList": [
+ {"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 2},
+ {"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1},
+ {"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1},
+ {"scope": "MatchScope_Match", "result": "ResultType_WinLoss", "winningTeamId": 1}]}}}}`)
+	return l
+}
+
+func testBestOfThree(t *testing.T, sideboard bool) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	_, err := p.Parse(newBo3Log(sideboard))
+	a.Nil(err)
+	p.Flush()
+	x := p.Log().Extract()
+	a.Len(x.Matches, 1)
+	m := x.Matches[0]
+	a.Equal("deck1", m.CourseDeck.ID)
+	a.Len(m.Games, 3)
+	for i, g := range m.Games {
+		a.NotNil(g.GameStart)
+		a.Equal(i+1, g.State().Current().GameInfo.GameNumber)
+		// Every game only saw its own card
+		a.Len(g.SeenObjects[1], 1)
+		a.Equal(60001+i, g.SeenObjects[1][0].GrpID)
+	}
+	a.Equal(1, *m.Games[0].Number)
+	a.Equal(2, *m.Games[1].Number)
+	a.Nil(m.Games[2].Number)
+	a.Equal(2, *m.Games[0].WinningTeamID)
+	a.Equal(1, *m.Games[1].WinningTeamID)
+	a.Equal(1, *m.Games[2].WinningTeamID)
+	a.Equal("ResultReason_Concede", *m.Games[2].WinningReason)
+	a.Equal(ResultLoss, m.Games[0].Result)
+	a.Equal(ResultWin, m.Games[1].Result)
+	a.Equal(ResultWin, m.Games[2].Result)
+	a.Equal(ResultWin, m.Result)
+	a.Equal("2-1", m.Score())
+
+	a.Equal(m.CourseDeck, m.Games[0].CourseDeck)
+	a.Nil(m.Games[0].SideboardDiff)
+	for _, g := range m.Games[1:] {
+		a.Equal("deck1", g.CourseDeck.ID)
+		if !sideboard {
+			a.Equal(m.CourseDeck.MainDeck, g.CourseDeck.MainDeck)
+			a.Empty(g.SideboardDiff.In)
+			a.Empty(g.SideboardDiff.Out)
+			continue
+		}
+		a.Equal([]ArenaDeckCard{{ID: 60001, Quantity: 3}, {ID: 70001, Quantity: 1}}, g.CourseDeck.MainDeck)
+		a.Equal([]ArenaDeckCard{{ID: 60001, Quantity: 1}}, g.CourseDeck.Sideboard)
+		a.Equal([]ArenaDeckCard{{ID: 70001, Quantity: 1}}, g.SideboardDiff.In)
+		a.Equal([]ArenaDeckCard{{ID: 60001, Quantity: 1}}, g.SideboardDiff.Out)
+	}
+}
+
+func TestBestOfThreeWithSideboarding(t *testing.T) {
+	testBestOfThree(t, true)
+}
+
+func TestBestOfThreeWithoutSideboarding(t *testing.T) {
+	testBestOfThree(t, false)
+}
@@ -1,7 +1,6 @@
 package gathering
 
 import (
-	"encoding/json"
 	"time"
 )
 
@@ -32,13 +31,13 @@ type Booster struct {
 
 // IsCrackBooster checks to see if the user opened a booster
 func (s *Segment) IsCrackBooster() bool {
-	return s.SegmentType == CrackBooster
+	return s.Is(CrackBooster)
 }
 
 // ParseCrackBooster parses a booster from the log
 func (s *Segment) ParseCrackBooster() (*Booster, error) {
 	var booster Booster
-	err := json.Unmarshal(stripNonJSON(s.Text), &booster)
+	err := s.decodeObject(&booster)
 	if s.Time != nil {
 		booster.OpenedAt = *s.Time
 	}
@@ -0,0 +1,139 @@
+package gathering
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Call is an API request paired with its response. Arena logs the request
+// (==> Method(id)) and the response (<== Method(id)) as separate segments,
+// often with other segments in between. Either side may be missing when the
+// log starts or ends between the two.
+type Call struct {
+	Method          string          `json:"method"`
+	Version         int             `json:"version"`
+	ID              int             `json:"id"`
+	Params          json.RawMessage `json:"params"`
+	Response        json.RawMessage `json:"response"`
+	RequestTime     *time.Time      `json:"requestTime"`
+	ResponseTime    *time.Time      `json:"responseTime"`
+	RequestSegment  *Segment        `json:"-"`
+	ResponseSegment *Segment        `json:"-"`
+}
+
+// Latency is how long the server took to respond, or 0 if we don't know both
+// times. The log only has second precision.
+func (c *Call) Latency() time.Duration {
+	if c.RequestTime == nil || c.ResponseTime == nil {
+		return 0
+	}
+	return c.ResponseTime.Sub(*c.RequestTime)
+}
+
+// rpcRequest is the JSON-RPC envelope a request's params are sent in
+type rpcRequest struct {
+	Params json.RawMessage `json:"params"`
+}
+
+// Calls pairs up all the requests and responses in the log, ordered by when
+// the request was made.
+func (l *Log) Calls() []*Call {
+	calls := make([]*Call, 0)
+	pairs := newCallPairer()
+	for _, s := range l.Segments {
+		if call, started := pairs.add(s); started {
+			calls = append(calls, call)
+		}
+	}
+	for _, call := range calls {
+		if s := call.RequestSegment; s != nil {
+			var req rpcRequest
+			if err := s.decodeObject(&req); err == nil {
+				call.Params = req.Params
+			}
+		}
+		if s := call.ResponseSegment; s != nil {
+			if p, ok := s.firstPayload(); ok {
+				call.Response = p.Raw
+			}
+		}
+	}
+	return calls
+}
+
+// maxPendingCalls is how many requests are waiting for a response before the
+// oldest are forgotten. Some requests are never answered in the log.
+const maxPendingCalls = 100
+
+// callPairer ties responses to their requests as the log is read. A response
+// belongs to the last request with the same id and method.
+type callPairer struct {
+	pending map[int]*pendingCall
+	added   int
+}
+
+// pendingCall is a request waiting for its response. n orders the requests,
+// so the oldest can be forgotten.
+type pendingCall struct {
+	call *Call
+	n    int
+}
+
+func newCallPairer() *callPairer {
+	return &callPairer{
+		pending: make(map[int]*pendingCall),
+	}
+}
+
+// add adds a segment to its call, returning the call and whether the segment
+// started it. Segments that aren't requests or responses return nil. Only the
+// segments and times are filled in.
+func (p *callPairer) add(s *Segment) (*Call, bool) {
+	m := s.Method
+	if m == nil {
+		return nil, false
+	}
+	if !m.Response {
+		call := newCall(m)
+		call.RequestSegment = s
+		call.RequestTime = s.Time
+		p.added++
+		p.pending[m.ID] = &pendingCall{call: call, n: p.added}
+		p.evict()
+		return call, true
+	}
+	var call *Call
+	started := false
+	if pending, ok := p.pending[m.ID]; ok && pending.call.Method == m.Name {
+		call = pending.call
+		delete(p.pending, m.ID)
+	} else {
+		// The request was before the start of the log
+		call = newCall(m)
+		started = true
+	}
+	call.ResponseSegment = s
+	call.ResponseTime = s.Time
+	return call, started
+}
+
+// evict forgets the oldest requests once too many are waiting
+func (p *callPairer) evict() {
+	for len(p.pending) > maxPendingCalls {
+		oldest, n := 0, p.added+1
+		for id, pending := range p.pending {
+			if pending.n < n {
+				oldest, n = id, pending.n
+			}
+		}
+		delete(p.pending, oldest)
+	}
+}
+
+func newCall(m *ArenaMethod) *Call {
+	return &Call{
+		Method:  m.Name,
+		Version: m.Version,
+		ID:      m.ID,
+	}
+}
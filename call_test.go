@@ -0,0 +1,109 @@
+package gathering
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogCalls(t *testing.T) {
+	a := assert.New(t)
+	f, err := os.Open("test/march-constructed.txt")
+	a.Nil(err)
+	alog, err := ParseLog(f)
+	a.Nil(err)
+	calls := alog.Calls()
+	var claim *Call
+	for _, c := range calls {
+		if c.Method == "Event.ClaimPrize" {
+			claim = c
+		}
+	}
+	a.NotNil(claim)
+	a.Equal(298, claim.ID)
+	a.JSONEq(`{"eventName": "Constructed_Event"}`, string(claim.Params))
+	a.NotNil(claim.RequestSegment)
+	a.True(claim.ResponseSegment.IsClaimPrize())
+	a.Contains(string(claim.Response), `"InternalEventName": "Constructed_Event"`)
+	// Log.Info responds with a bare `True`, which isn't a payload
+	a.Equal("Log.Info", calls[0].Method)
+	a.Equal(290, calls[0].ID)
+	a.NotNil(calls[0].ResponseSegment)
+	a.Nil(calls[0].Response)
+}
+
+func TestCallLatency(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	_, err := p.Parse(bytes.NewBufferString(`[UnityCrossThreadLogger]4/2/2019 3:01:48 PM
+==> Event.GetPlayerCourseV2(296):
+{"jsonrpc": "2.0", "method": "Event.GetPlayerCourseV2", "params": {"eventName": "Constructed_Event"}, "id": "296"}
+[UnityCrossThreadLogger]4/2/2019 3:01:48 PM
+==> Deck.GetDeckListsV3(297):
+{"jsonrpc": "2.0", "method": "Deck.GetDeckListsV3", "params": {}, "id": "297"}
+[UnityCrossThreadLogger]4/2/2019 3:01:50 PM
+<== Event.GetPlayerCourseV2(296)
+{"Id": "00f2bcfe-e5c3-45ba-9950-05d10d2687ad"}
+`))
+	a.Nil(err)
+	p.Flush()
+	calls := p.Log().Calls()
+	a.Len(calls, 2)
+	a.Equal("Event.GetPlayerCourse", calls[0].Method)
+	a.Equal(2, calls[0].Version)
+	a.Equal(2*time.Second, calls[0].Latency())
+	a.JSONEq(`{"Id": "00f2bcfe-e5c3-45ba-9950-05d10d2687ad"}`, string(calls[0].Response))
+	// Still waiting for the response
+	a.Equal("Deck.GetDeckLists", calls[1].Method)
+	a.Nil(calls[1].ResponseSegment)
+	a.Equal(time.Duration(0), calls[1].Latency())
+}
+
+func TestCallMissingRequest(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	_, err := p.Parse(bytes.NewBufferString(`[UnityCrossThreadLogger]4/2/2019 3:01:50 PM
+<== Event.GetPlayerCourseV2(296)
+{"Id": "00f2bcfe-e5c3-45ba-9950-05d10d2687ad"}
+`))
+	a.Nil(err)
+	p.Flush()
+	calls := p.Log().Calls()
+	a.Len(calls, 1)
+	a.Nil(calls[0].RequestSegment)
+	a.Nil(calls[0].Params)
+	a.NotNil(calls[0].ResponseSegment)
+}
+
+func TestCallPairerOtherMethod(t *testing.T) {
+	a := assert.New(t)
+	p := newCallPairer()
+	request := &Segment{Method: &ArenaMethod{Name: "Deck.GetDeckLists", ID: 5}}
+	other := &Segment{Method: &ArenaMethod{Name: "Event.GetPlayerCourse", ID: 5, Response: true}}
+	response := &Segment{Method: &ArenaMethod{Name: "Deck.GetDeckLists", ID: 5, Response: true}}
+	p.add(request)
+	// A response to another method with the same id leaves the request waiting
+	call, started := p.add(other)
+	a.True(started)
+	a.Nil(call.RequestSegment)
+	call, started = p.add(response)
+	a.False(started)
+	a.Equal(request, call.RequestSegment)
+	a.Len(p.pending, 0)
+}
+
+func TestCallPairerForgetsOldRequests(t *testing.T) {
+	a := assert.New(t)
+	p := newCallPairer()
+	for id := 1; id <= maxPendingCalls+10; id++ {
+		p.add(&Segment{Method: &ArenaMethod{Name: "Quest.GetPlayerQuests", ID: id}})
+	}
+	a.Len(p.pending, maxPendingCalls)
+	_, ok := p.pending[10]
+	a.False(ok)
+	_, ok = p.pending[11]
+	a.True(ok)
+}
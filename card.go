@@ -0,0 +1,45 @@
+package gathering
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Card is what we know about a card from a card database
+type Card struct {
+	GrpID    int      `json:"grpId"`
+	Name     string   `json:"name"`
+	Set      string   `json:"set"`
+	Number   string   `json:"number"`
+	ManaCost string   `json:"manaCost"`
+	Colors   []string `json:"colors"`
+	Types    []string `json:"types"`
+}
+
+// CardDatabase looks up cards by grpId. The log only has grpIds, so anything
+// that needs names or card text takes one. Plug in whatever source you have.
+type CardDatabase interface {
+	Card(grpID int) (*Card, bool)
+}
+
+// CardMap is a CardDatabase held in memory
+type CardMap map[int]*Card
+
+// Card looks up a card
+func (m CardMap) Card(grpID int) (*Card, bool) {
+	c, ok := m[grpID]
+	return c, ok
+}
+
+// ReadCardMap reads a CardMap from a JSON list of cards
+func ReadCardMap(r io.Reader) (CardMap, error) {
+	var cards []*Card
+	if err := json.NewDecoder(r).Decode(&cards); err != nil {
+		return nil, err
+	}
+	m := make(CardMap)
+	for _, c := range cards {
+		m[c.GrpID] = c
+	}
+	return m, nil
+}
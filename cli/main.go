@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/user"
@@ -16,10 +17,19 @@ import (
 	"github.com/gathering-gg/parser"
 	"github.com/gathering-gg/parser/api"
 	"github.com/gathering-gg/parser/config"
+	"github.com/gathering-gg/parser/odds"
 )
 
 const fileName = "output_log.txt"
 
+// dateOrders are the values of the -dateorder flag
+var dateOrders = map[string]gathering.DateOrder{
+	"":    gathering.DateOrderAuto,
+	"mdy": gathering.MonthDayYear,
+	"dmy": gathering.DayMonthYear,
+	"ymd": gathering.YearMonthDay,
+}
+
 func debugJ(format string, o interface{}) (n int, err error) {
 	b, _ := json.Marshal(o)
 	return debug(format, string(b[:]))
@@ -67,77 +77,80 @@ func upload(loc string) error {
 	return nil
 }
 
-// ParseAll gets all data from a log
-func ParseAll(filePath string) (gathering.UploadData, error) {
-	data := gathering.UploadData{}
-	f, err := os.Open(filePath)
-	if err != nil {
-		return data, err
+// loadCheckpoint reads a saved checkpoint. A missing or unreadable file just
+// means we parse the log from the beginning.
+func loadCheckpoint(loc string) *gathering.Checkpoint {
+	if loc == "" {
+		return nil
 	}
-	alog, err := gathering.ParseLog(f)
+	b, err := ioutil.ReadFile(loc)
 	if err != nil {
-		return data, err
+		if !os.IsNotExist(err) {
+			log.Printf("error reading checkpoint: %v\n", err.Error())
+		}
+		return nil
 	}
-	col, err := alog.Collection()
-	if err != nil {
-		log.Printf("error getting collection: %v\n", err.Error())
-	} else {
-		debugJ("***collection %v", col)
-		data.Collection = col
+	var cp gathering.Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		log.Printf("error parsing checkpoint: %v\n", err.Error())
+		return nil
 	}
-	rank, err := alog.Rank()
-	if err != nil {
-		log.Printf("error getting rank: %v\n", err.Error())
-	} else {
-		debugJ("***rank %v", rank)
-		data.Rank = rank
+	return &cp
+}
+
+// saveCheckpoint stores where the parser got to, with the segments the
+// extractor needs to carry on, so a restart doesn't have to parse the log
+// from the beginning
+func saveCheckpoint(loc string, p *gathering.LogParser, x *gathering.Extractor) {
+	if loc == "" {
+		return
 	}
-	inv, err := alog.Inventory()
+	cp := p.Checkpoint()
+	cp.Keep(x.Kept())
+	b, err := json.Marshal(cp)
 	if err != nil {
-		log.Printf("error getting inventory: %v\n", err.Error())
-	} else {
-		debugJ("***inv %v", inv)
-		data.Inventory = inv
+		log.Printf("error creating checkpoint: %v\n", err.Error())
+		return
 	}
-	name, err := alog.Auth()
-	if err != nil {
-		log.Printf("error getting auth: %v\n", err.Error())
-	} else {
-		// TODO: Make this prettier, we only need the name
-		data.Auth = &gathering.ArenaAuthRequest{
-			Payload: gathering.ArenaAuthRequestPayload{
-				PlayerName: string(name),
-			},
-		}
+	if err := ioutil.WriteFile(loc, b, 0644); err != nil {
+		log.Printf("error saving checkpoint: %v\n", err.Error())
 	}
-	decks, err := alog.Decks()
+}
+
+// ParseAll gets all data from a log. Only the data appended since the last call
+// is read, the rest is already in the parser and the tracker's extractor.
+func ParseAll(parser *gathering.LogParser, tracker *gathering.Tracker, filePath string) (gathering.UploadData, error) {
+	data := gathering.UploadData{}
+	f, err := os.Open(filePath)
 	if err != nil {
-		log.Printf("error getting decks: %v\n", err.Error())
-	} else {
-		debugJ("***decks %v", decks)
-		data.Decks = decks
+		return data, err
 	}
-	boosters, err := alog.Boosters()
+	defer f.Close()
+	segments, truncated, err := parser.ParseFile(f)
 	if err != nil {
-		log.Printf("error getting boosters: %v\n", err.Error())
-	} else {
-		debugJ("***boosters %v", boosters)
-		data.Boosters = boosters
+		return data, err
 	}
-	matches, err := alog.Matches()
-	if err != nil {
-		log.Printf("error getting matches: %v\n", err.Error())
-	} else {
-		debugJ("***matches %v", matches)
-		data.Matches = matches
+	if truncated {
+		log.Println("log file was truncated, parsing from the beginning")
+		tracker.Reset()
 	}
-	events, err := alog.Events()
-	if err != nil {
-		log.Printf("error getting events: %v\n", err.Error())
-	} else {
-		debugJ("***events %v", events)
-		data.Events = events
+	tracker.Feed(segments)
+	// The log only has the errors of this parse
+	for _, e := range parser.Log().Errors {
+		log.Printf("error parsing log file: %v\n", e.Error())
 	}
+	extracted := tracker.Extractor().Extraction()
+	for _, e := range extracted.Errors {
+		log.Printf("error getting %v\n", e.Error())
+	}
+	data = extracted.UploadData
+	debugJ("***collection %v", data.Collection)
+	debugJ("***rank %v", data.Rank)
+	debugJ("***inv %v", data.Inventory)
+	debugJ("***decks %v", data.Decks)
+	debugJ("***boosters %v", data.Boosters)
+	debugJ("***matches %v", data.Matches)
+	debugJ("***events %v", data.Events)
 	running, err := gathering.IsArenaRunning()
 	if err != nil {
 		log.Printf("error getting mtga.exe running status: %v\n", err.Error())
@@ -148,9 +161,9 @@ func ParseAll(filePath string) (gathering.UploadData, error) {
 }
 
 // onChange parse out all info and upload to the server
-func onChange(f string) {
+func onChange(parser *gathering.LogParser, tracker *gathering.Tracker, f string) error {
 	log.Println("log file updated, parsing")
-	body, err := ParseAll(f)
+	body, err := ParseAll(parser, tracker, f)
 	if err != nil {
 		log.Printf("error parsing log file: %v\n", err.Error())
 	}
@@ -158,15 +171,38 @@ func onChange(f string) {
 	req, err := api.Upload("/upload/json", body)
 	if err != nil {
 		log.Printf("error creating request: %v\n", err.Error())
-		return
+		return err
 	}
 	var mes bytes.Buffer
 	_, err = api.Do(req, &mes)
 	if err != nil {
 		log.Printf("error uploading data: %v\n", err.Error())
-		return
+		return err
 	}
 	log.Printf("upload success! Server => %v", mes.String())
+	return nil
+}
+
+// showOdds logs what is left in the player's library in the game being
+// played, and the chance of drawing each card by their next turn
+func showOdds(tracker *gathering.Tracker) {
+	match := tracker.Match()
+	live := tracker.Live()
+	if match == nil || live == nil || live.Done || live.SeatID == 0 ||
+		live.GameNumber < 1 || live.GameNumber > len(match.Games) {
+		return
+	}
+	game := match.Games[live.GameNumber-1]
+	if game.CourseDeck == nil {
+		return
+	}
+	state := game.State().Current()
+	lib := odds.Remaining(game.CourseDeck, state, live.SeatID)
+	draws := odds.DrawsUntil(state, live.SeatID, state.Turn()+2)
+	log.Printf("library: %v cards, %v draws by your next turn\n", lib.Size, draws)
+	for _, c := range lib.Composition(draws) {
+		log.Printf("  %v x%v: %.1f%% next draw, %.1f%% by next turn\n", c.GrpID, c.Copies, c.NextDraw*100, c.ByTurn*100)
+	}
 }
 
 // main
@@ -177,11 +213,33 @@ func main() {
 	var uploadFlag = flag.Bool("upload", false, "Upload the log file instead of parsing. If provided, the client will not continue running, but will parse once, upload, and exit.")
 	var versionFlag = flag.Bool("version", false, "Show the current running version")
 	var timerFlag = flag.Int("timer", 30, "How often do you want the log file to be read in seconds? Changing this to be higher will delay updates to gathering.gg, but will increase performance. Defaults to 30 seconds")
+	var checkpointFlag = flag.String("checkpoint", "", "A file to save the parse position in. When set, restarting the client continues parsing where it left off instead of reading the whole log again.")
+	var oddsFlag = flag.Bool("odds", false, "Show what is left in your library and the odds of drawing each card while a game is being played.")
+	var timezoneFlag = flag.String("timezone", "", "The time zone the log's timestamps are written in, like `Europe/Paris`. Defaults to the local time zone.")
+	var dateOrderFlag = flag.String("dateorder", "", "The order of the day and month in the log's timestamps: mdy, dmy or ymd. Worked out from the log when not given.")
 	flag.Parse()
 	if *versionFlag {
 		fmt.Println(config.Version)
 		return
 	}
+	location := time.Local
+	if *timezoneFlag != "" {
+		loc, err := time.LoadLocation(*timezoneFlag)
+		if err != nil {
+			log.Fatalf("Error, unknown time zone '%v': %v\n", *timezoneFlag, err.Error())
+		}
+		location = loc
+	}
+	dateOrder, ok := dateOrders[strings.ToLower(*dateOrderFlag)]
+	if !ok {
+		log.Fatalf("Error, unknown date order '%v', use mdy, dmy or ymd\n", *dateOrderFlag)
+	}
+	newParser := func(cp *gathering.Checkpoint) *gathering.LogParser {
+		p := gathering.NewLogParser(cp)
+		p.Location = location
+		p.DateOrder = dateOrder
+		return p
+	}
 	log.Println("gathering.gg client starting")
 	if *tokenFlag == "" {
 		log.Fatalln("Error, need authentication token to upload data! Use `-token=TOKEN`")
@@ -204,7 +262,7 @@ func main() {
 	// is and can parse the log file and begin the watch loop.
 	if *uploadFlag {
 		log.Println("Uploading raw log file (this may take a while)")
-		onChange(file)
+		onChange(newParser(nil), gathering.NewTracker(nil), file)
 		upload(file)
 		return
 	}
@@ -213,6 +271,12 @@ func main() {
 	// Checks the file size every X duration and on change will fire the
 	// event. Easier to use and manage and sure to work.
 	watcher := NewWatcher(file, time.Duration(*timerFlag)*time.Second)
+	parser := newParser(loadCheckpoint(*checkpointFlag))
+	parser.Discard = true
+	tracker := gathering.NewTracker(nil)
+	extractor := tracker.Extractor()
+	// A resumed parser starts with what the checkpoint kept
+	tracker.Feed(parser.Log().Segments)
 	defer watcher.Stop()
 	done := make(chan bool)
 	log.Printf("adding log file location and watching: %v\n", file)
@@ -222,7 +286,14 @@ func main() {
 			select {
 			case event := <-watcher.Events:
 				log.Println("file updated, size:", siformat(event.Size))
-				onChange(file)
+				if err := onChange(parser, tracker, file); err == nil {
+					saveCheckpoint(*checkpointFlag, parser, extractor)
+					// Uploaded, only what is still needed is kept
+					extractor.Trim()
+				}
+				if *oddsFlag {
+					showOdds(tracker)
+				}
 			case err := <-watcher.Errors:
 				log.Println("watcher error:", err)
 				if strings.Index(err.Error(), "no such file or directory") > -1 {
@@ -231,6 +302,11 @@ func main() {
 			}
 		}
 	}()
+	go func() {
+		for live := range tracker.Subscribe() {
+			debugJ("***live %v\n", live)
+		}
+	}()
 	log.Println("entering main watch loop")
 	watcher.Start()
 	<-done
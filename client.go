@@ -0,0 +1,160 @@
+package gathering
+
+import (
+	"encoding/json"
+)
+
+// ClientToGRE messages are logged as
+// `[Client GRE]... to Match: ClientToMatchServiceMessageType_ClientToGREMessage`
+const clientToGREMessage = "ClientToMatchServiceMessageType_ClientToGREMessage"
+
+// The ClientToGREMessage types
+const (
+	ClientSubmitDeckResp       = "ClientMessageType_SubmitDeckResp"
+	ClientPerformActionResp    = "ClientMessageType_PerformActionResp"
+	ClientDeclareAttackersResp = "ClientMessageType_DeclareAttackersResp"
+	ClientDeclareBlockersResp  = "ClientMessageType_DeclareBlockersResp"
+	ClientSelectTargetsResp    = "ClientMessageType_SelectTargetsResp"
+	ClientConcedeReq           = "ClientMessageType_ConcedeReq"
+)
+
+// ArenaClientMessage is the envelope the client sends GRE messages in. Older
+// clients log the payload as JSON, newer ones as an encoded string, which we
+// can't read; Payload is nil for those.
+type ArenaClientMessage struct {
+	RequestID     int                 `json:"requestId"`
+	Timestamp     string              `json:"timestamp"`
+	TransactionID string              `json:"transactionId"`
+	Payload       *ClientToGREMessage `json:"-"`
+}
+
+// ClientToGREMessage is something the player did, or a response to a GRE
+// request
+type ClientToGREMessage struct {
+	Type                 string                `json:"type"`
+	GameStateID          int                   `json:"gameStateId"`
+	RespID               int                   `json:"respId"`
+	SubmitDeckResp       *SubmitDeckResp       `json:"submitDeckResp"`
+	PerformActionResp    *PerformActionResp    `json:"performActionResp"`
+	DeclareAttackersResp *DeclareAttackersResp `json:"declareAttackersResp"`
+	DeclareBlockersResp  *DeclareBlockersResp  `json:"declareBlockersResp"`
+	SelectTargetsResp    *SelectTargetsResp    `json:"selectTargetsResp"`
+	ConcedeReq           *ConcedeReq           `json:"concedeReq"`
+}
+
+// PerformActionResp is the action the player took when they had priority
+type PerformActionResp struct {
+	Actions []GREAction `json:"actions"`
+}
+
+// GREAction is an action like casting a spell or playing a land
+type GREAction struct {
+	ActionType   string `json:"actionType"`
+	GrpID        int    `json:"grpId"`
+	InstanceID   int    `json:"instanceId"`
+	AbilityGrpID int    `json:"abilityGrpId"`
+}
+
+// DeclareAttackersResp has the creatures the player attacked with
+type DeclareAttackersResp struct {
+	SelectedAttackers []SelectedAttacker `json:"selectedAttackers"`
+}
+
+// SelectedAttacker is an attacking creature
+type SelectedAttacker struct {
+	AttackerInstanceID int `json:"attackerInstanceId"`
+}
+
+// DeclareBlockersResp has the creatures the player blocked with
+type DeclareBlockersResp struct {
+	SelectedBlockers []SelectedBlocker `json:"selectedBlockers"`
+}
+
+// SelectedBlocker is a blocking creature and the attackers it blocks
+type SelectedBlocker struct {
+	BlockerInstanceID           int   `json:"blockerInstanceId"`
+	SelectedAttackerInstanceIDs []int `json:"selectedAttackerInstanceIds"`
+}
+
+// SelectTargetsResp has the targets the player chose
+type SelectTargetsResp struct {
+	Target TargetSelection `json:"target"`
+}
+
+// TargetSelection is the targets chosen for one target of a spell or ability
+type TargetSelection struct {
+	TargetIdx int      `json:"targetIdx"`
+	Targets   []Target `json:"targets"`
+}
+
+// Target is a chosen target
+type Target struct {
+	TargetInstanceID int `json:"targetInstanceId"`
+}
+
+// ConcedeReq is the player conceding the game or match
+type ConcedeReq struct {
+	Scope string `json:"scope"`
+}
+
+// SubmitDeckResp is the deck the player submits for the next game, after
+// sideboarding
+type SubmitDeckResp struct {
+	Deck DeckMessage `json:"deck"`
+}
+
+// DeckMessage is a deck as the GRE sees it, with one grpId for every copy of
+// a card
+type DeckMessage struct {
+	DeckCards      []int `json:"deckCards"`
+	SideboardCards []int `json:"sideboardCards"`
+}
+
+// ArenaDeck converts the deck to our usual format, keeping the order cards
+// first appear in
+func (d *DeckMessage) ArenaDeck() *ArenaDeck {
+	return &ArenaDeck{
+		MainDeck:  countCards(d.DeckCards),
+		Sideboard: countCards(d.SideboardCards),
+	}
+}
+
+func countCards(ids []int) []ArenaDeckCard {
+	cards := []ArenaDeckCard{}
+	index := make(map[int]int)
+	for _, id := range ids {
+		if i, ok := index[id]; ok {
+			cards[i].Quantity++
+			continue
+		}
+		index[id] = len(cards)
+		cards = append(cards, ArenaDeckCard{ID: id, Quantity: 1})
+	}
+	return cards
+}
+
+// IsClientToGRE checks if this segment is a message from the client to the
+// GRE
+func (s *Segment) IsClientToGRE() bool {
+	return s.Is(ClientToGRE)
+}
+
+// ParseClientToGRE parses a message from the client to the GRE
+func (s *Segment) ParseClientToGRE() (*ArenaClientMessage, error) {
+	var envelope struct {
+		ArenaClientMessage
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := s.decodeObject(&envelope); err != nil {
+		return nil, err
+	}
+	msg := envelope.ArenaClientMessage
+	if len(envelope.Payload) > 0 && envelope.Payload[0] == '{' {
+		var payload ClientToGREMessage
+		if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return &msg, err
+		}
+		msg.Payload = &payload
+	}
+	return &msg, nil
+}
@@ -0,0 +1,31 @@
+package gathering
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClientToGREEncoded(t *testing.T) {
+	a := assert.New(t)
+	s := &Segment{Text: []byte(`{
+  "requestId": 191,
+  "clientToMatchServiceMessageType": "ClientToMatchServiceMessageType_ClientToGREMessage",
+  "payload": "CDUQAcICBQoBAhoA"
+}`)}
+	msg, err := s.ParseClientToGRE()
+	a.Nil(err)
+	a.Equal(191, msg.RequestID)
+	a.Nil(msg.Payload)
+}
+
+func TestDeckMessage(t *testing.T) {
+	a := assert.New(t)
+	d := &DeckMessage{
+		DeckCards:      []int{3, 1, 3, 3},
+		SideboardCards: []int{},
+	}
+	deck := d.ArenaDeck()
+	a.Equal([]ArenaDeckCard{{ID: 3, Quantity: 3}, {ID: 1, Quantity: 1}}, deck.MainDeck)
+	a.Equal([]ArenaDeckCard{}, deck.Sideboard)
+}
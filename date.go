@@ -0,0 +1,319 @@
+package gathering
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOrder is the order of the day, month and year in the log's timestamps.
+// Arena writes them in the format of the system locale, so it differs between
+// players.
+type DateOrder int
+
+// The date orders we understand. DateOrderAuto works the order out from the
+// log itself.
+const (
+	DateOrderAuto DateOrder = iota
+	MonthDayYear
+	DayMonthYear
+	YearMonthDay
+)
+
+// Matches all of:
+// 1/8/2019 2:07:00 PM
+// 08.01.2019 14:07:00
+// 2019-01-08T14:07:00
+// 2019/01/08 14:07:00
+var findDate = regexp.MustCompile(`(\d{1,4})[/.\-](\d{1,2})[/.\-](\d{1,4})[T,\s]+(\d{1,2}):(\d{2}):(\d{2})(?:\s*([AaPp])\.?[Mm]\.?)?`)
+
+// rawDate is a timestamp found in a line, before we know what the numbers mean
+type rawDate struct {
+	first    int
+	second   int
+	third    int
+	iso      bool
+	hour     int
+	minute   int
+	sec      int
+	meridiem byte
+}
+
+func findRawDate(line []byte) (*rawDate, bool) {
+	m := findDate.FindSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	n := make([]int, 6)
+	for i := range n {
+		n[i], _ = strconv.Atoi(string(m[i+1]))
+	}
+	d := &rawDate{
+		first:  n[0],
+		second: n[1],
+		third:  n[2],
+		iso:    len(m[1]) == 4,
+		hour:   n[3],
+		minute: n[4],
+		sec:    n[5],
+	}
+	if len(m[7]) > 0 {
+		d.meridiem = m[7][0] | 0x20 // lower case
+	}
+	return d, true
+}
+
+// order is what the date's numbers say about the order. Dates where both the
+// day and month could be either return DateOrderAuto.
+func (d *rawDate) order() DateOrder {
+	switch {
+	case d.iso:
+		return YearMonthDay
+	case d.first > 12:
+		return DayMonthYear
+	case d.second > 12:
+		return MonthDayYear
+	}
+	return DateOrderAuto
+}
+
+// time converts the date, reading the day and month in the given order. 12
+// hour clocks are detected from the AM/PM marker.
+func (d *rawDate) time(order DateOrder, loc *time.Location) *time.Time {
+	year, month, day := d.third, d.first, d.second
+	switch {
+	case d.iso:
+		year, month, day = d.first, d.second, d.third
+	case order == DayMonthYear:
+		month, day = d.second, d.first
+	}
+	hour := d.hour
+	if d.meridiem != 0 {
+		if hour < 1 || hour > 12 {
+			return nil
+		}
+		hour = hour % 12
+		if d.meridiem == 'p' {
+			hour += 12
+		}
+	}
+	if month < 1 || month > 12 || hour > 23 || d.minute > 59 || d.sec > 59 {
+		return nil
+	}
+	t := time.Date(year, time.Month(month), day, hour, d.minute, d.sec, 0, loc)
+	if t.Day() != day {
+		// Like 31/4, which time.Date would roll over to 1/5
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+func (p *LogParser) location() *time.Location {
+	if p.Location != nil {
+		return p.Location
+	}
+	return time.Local
+}
+
+// setTime finds the timestamp of a segment in its header line. Until a date
+// in the log shows whether the day or month comes first, month first is
+// assumed, and those segments are fixed up once we know.
+func (p *LogParser) setTime(s *Segment) {
+	d, ok := findRawDate(s.Line)
+	if !ok {
+		return
+	}
+	order := p.DateOrder
+	if order == DateOrderAuto && p.order == DateOrderAuto {
+		if o := d.order(); o == MonthDayYear || o == DayMonthYear {
+			p.order = o
+			p.redate()
+		}
+	}
+	if order == DateOrderAuto {
+		order = p.order
+	}
+	if order == DateOrderAuto {
+		if !d.iso {
+			p.undated = append(p.undated, s)
+		}
+		order = MonthDayYear
+	}
+	s.Time = d.time(order, p.location())
+}
+
+// redate parses the times of the segments we guessed at again, now that we
+// know the order, and interpolates the times that were worked out from them
+// again
+func (p *LogParser) redate() {
+	for _, s := range p.undated {
+		if d, ok := findRawDate(s.Line); ok {
+			retime(s, d.time(p.order, p.location()))
+		}
+	}
+	p.undated = nil
+	guessed := p.guessed
+	p.guessed = nil
+	p.lastTime = p.guessedFrom
+	p.untimed = nil
+	for _, g := range guessed {
+		if !g.own {
+			p.untimed = append(p.untimed, g.segment)
+			continue
+		}
+		p.interpolate(g.segment)
+	}
+}
+
+// retime changes the time of a segment that may already have been handed
+// out. The time is changed in place, so whatever took it from the segment,
+// like a match's GameStart, has the new one too.
+func retime(s *Segment, t *time.Time) {
+	if s.Time == nil || t == nil {
+		s.Time = t
+		return
+	}
+	*s.Time = *t
+}
+
+// guessedTime is a segment finished while the date order was a guess. own is
+// set when the time came from the segment itself rather than interpolation.
+type guessedTime struct {
+	segment *Segment
+	own     bool
+}
+
+// ticksToUnix is the Unix epoch in .NET ticks, 100ns since 1/1/0001
+const ticksToUnix = 621355968000000000
+
+// payloadTimestamp finds the top level "timestamp" GRE messages carry in
+// their payload. Only the keys before it are read, so large payloads aren't
+// decoded just for their time.
+func (s *Segment) payloadTimestamp() json.RawMessage {
+	for _, p := range extractJSON(s.Text) {
+		if p.Raw[0] != '{' {
+			continue
+		}
+		t, err := topLevelKey(p.Raw, "timestamp")
+		if _, ok := err.(*json.SyntaxError); ok {
+			continue
+		}
+		return t
+	}
+	return nil
+}
+
+// topLevelKey walks the object in b up to key and returns its value, or nil
+// if the object doesn't have it
+func topLevelKey(b []byte, key string) (json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		k, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if k == key {
+			return value, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseEpoch reads the timestamps of GRE messages. Arena writes milliseconds
+// since the Unix epoch, and in some messages .NET ticks, always as strings.
+func parseEpoch(b []byte) *time.Time {
+	n, err := strconv.ParseInt(strings.Trim(string(b), `"`), 10, 64)
+	if err != nil || n <= 0 {
+		return nil
+	}
+	var t time.Time
+	switch {
+	case n > 1e17:
+		n -= ticksToUnix
+		t = time.Unix(n/1e7, n%1e7*100)
+	case n > 1e11:
+		t = time.Unix(n/1e3, n%1e3*1e6)
+	default:
+		t = time.Unix(n, 0)
+	}
+	t = t.UTC()
+	return &t
+}
+
+// fillTime gives segments without a date in their header line one. It comes
+// from the timestamp in the payload if there is one, otherwise it is
+// interpolated between the segments either side once the next one with a time
+// is found.
+func (p *LogParser) fillTime(s *Segment) {
+	if len(s.Line) == 0 {
+		return
+	}
+	if s.Time == nil {
+		if t := s.payloadTimestamp(); len(t) > 0 {
+			s.Time = parseEpoch(t)
+		}
+	}
+	if len(p.undated) > 0 {
+		p.guess(s)
+	}
+	p.interpolate(s)
+}
+
+// guess remembers a segment whose time may change once the date order is
+// known, along with the segments waiting for a time when the guessing started
+func (p *LogParser) guess(s *Segment) {
+	if p.guessed == nil {
+		p.guessedFrom = p.lastTime
+		for _, u := range p.untimed {
+			p.guessed = append(p.guessed, guessedTime{segment: u})
+		}
+	}
+	p.guessed = append(p.guessed, guessedTime{segment: s, own: s.Time != nil})
+}
+
+// interpolate fills in the times of the segments waiting for one, if s has a
+// time, or adds s to them
+func (p *LogParser) interpolate(s *Segment) {
+	if s.Time == nil {
+		p.untimed = append(p.untimed, s)
+		return
+	}
+	from := s.Time
+	if p.lastTime != nil {
+		from = p.lastTime
+	}
+	step := s.Time.Sub(*from) / time.Duration(len(p.untimed)+1)
+	if step < 0 {
+		// The clock went backwards, don't make it worse
+		step = 0
+	}
+	for i, u := range p.untimed {
+		t := from.Add(step * time.Duration(i+1))
+		retime(u, &t)
+	}
+	p.untimed = nil
+	p.lastTime = s.Time
+}
+
+// fillTrailing gives the segments after the last one with a time that time
+func (p *LogParser) fillTrailing() {
+	if p.lastTime == nil {
+		return
+	}
+	for _, u := range p.untimed {
+		t := *p.lastTime
+		u.Time = &t
+	}
+	p.untimed = nil
+}
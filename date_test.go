@@ -0,0 +1,203 @@
+package gathering
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseDates(t *testing.T, p *LogParser, lines ...string) []*Segment {
+	var b bytes.Buffer
+	for _, l := range lines {
+		b.WriteString("[UnityCrossThreadLogger]" + l + "\n{}\n")
+	}
+	_, err := p.Parse(&b)
+	assert.Nil(t, err)
+	p.Flush()
+	return p.Log().Segments[1:]
+}
+
+func utc(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
+
+func TestParseDateFormats(t *testing.T) {
+	a := assert.New(t)
+	tests := []struct {
+		line string
+		want time.Time
+	}{
+		{"1/8/2019 3:07:00 PM", utc(2019, 1, 8, 15, 7, 0)},
+		{"1/8/2019 12:07:00 AM", utc(2019, 1, 8, 0, 7, 0)},
+		{"1/8/2019 12:07:00 PM", utc(2019, 1, 8, 12, 7, 0)},
+		{"2019-01-08T15:07:00", utc(2019, 1, 8, 15, 7, 0)},
+		{"2019/01/08 15:07:00", utc(2019, 1, 8, 15, 7, 0)},
+		{"13.01.2019 15:07:00", utc(2019, 1, 13, 15, 7, 0)},
+		{"1/13/2019 3:07:00 p.m.", utc(2019, 1, 13, 15, 7, 0)},
+	}
+	for _, test := range tests {
+		p := NewLogParser(nil)
+		p.Location = time.UTC
+		s := parseDates(t, p, test.line)
+		if a.NotNil(s[0].Time, test.line) {
+			a.Equal(test.want, *s[0].Time, test.line)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	s := parseDates(t, p, "13/13/2019 15:07:00", "1/8/2019 13:07:00 PM", "no date here")
+	for _, seg := range s {
+		a.Nil(seg.Time)
+	}
+}
+
+func TestParseDateDetectsOrder(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	p.Location = time.UTC
+	s := parseDates(t, p, "02.03.2019 10:00:00", "25.03.2019 10:00:00", "04.03.2019 10:00:00")
+	// The first date was ambiguous until the second showed the day comes first
+	a.Equal(utc(2019, 3, 2, 10, 0, 0), *s[0].Time)
+	a.Equal(utc(2019, 3, 25, 10, 0, 0), *s[1].Time)
+	a.Equal(utc(2019, 3, 4, 10, 0, 0), *s[2].Time)
+	a.Equal(DayMonthYear, p.Checkpoint().DateOrder)
+}
+
+func TestParseDateOrderFromCheckpoint(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(&Checkpoint{DateOrder: DayMonthYear})
+	p.Location = time.UTC
+	s := parseDates(t, p, "02/03/2019 10:00:00")
+	a.Equal(utc(2019, 3, 2, 10, 0, 0), *s[0].Time)
+}
+
+func TestParseDateForcedOrder(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	p.Location = time.UTC
+	p.DateOrder = MonthDayYear
+	s := parseDates(t, p, "02/03/2019 10:00:00", "25/03/2019 10:00:00")
+	a.Equal(utc(2019, 2, 3, 10, 0, 0), *s[0].Time)
+	// Doesn't fit the forced order, so it takes the time before it
+	a.Equal(*s[0].Time, *s[1].Time)
+}
+
+func TestParseDateLocation(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	p.Location = time.FixedZone("UTC+2", 2*60*60)
+	s := parseDates(t, p, "2019-01-08T15:07:00")
+	a.Equal(utc(2019, 1, 8, 13, 7, 0), *s[0].Time)
+}
+
+func TestParseEpoch(t *testing.T) {
+	a := assert.New(t)
+	want := utc(2019, 4, 2, 19, 1, 41)
+	a.Equal(want.Add(5*time.Millisecond), *parseEpoch([]byte(`"1554231701005"`)))
+	a.Equal(want.Add(5204100), *parseEpoch([]byte(`"636898285010052041"`)))
+	a.Equal(want, *parseEpoch([]byte(`1554231701`)))
+	a.Nil(parseEpoch([]byte(`"soon"`)))
+}
+
+func TestPayloadTimestamp(t *testing.T) {
+	a := assert.New(t)
+	s := &Segment{Text: []byte(`[Client GRE]GREConnection.ProcessMessage
+{"greToClientEvent": {"timestamp": "1"}, "timestamp": "1554231701005", "gameStateMessage": {}}
+`)}
+	a.Equal(`"1554231701005"`, string(s.payloadTimestamp()))
+	s.Text = []byte(`[UnityCrossThreadLogger]{"greToClientEvent": {"timestamp": "1"}}`)
+	a.Nil(s.payloadTimestamp())
+}
+
+func TestFillSegmentTimes(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	p.Location = time.UTC
+	_, err := p.Parse(bytes.NewBufferString(`[Client GRE]GREConnection.Connect
+{}
+[UnityCrossThreadLogger]4/2/2019 7:01:40 PM
+{}
+[Client GRE]GREConnection.ProcessMessage
+{"timestamp": "1554231701005"}
+[Client GRE]GREConnection.ProcessMessage
+{}
+[Client GRE]GREConnection.ProcessMessage
+{}
+[Client GRE]GREConnection.ProcessMessage
+{"timestamp": "1554231704005"}
+[Client GRE]GREConnection.ProcessMessage
+{}
+`))
+	a.Nil(err)
+	s := p.Log().Segments
+	// The last segment is still open, and nothing after it has a time yet
+	a.Len(s, 7)
+	first := utc(2019, 4, 2, 19, 1, 40)
+	a.Nil(s[0].Time)
+	a.Equal(first, *s[1].Time)
+	a.Equal(first, *s[2].Time)
+	a.Equal(first.Add(1005*time.Millisecond), *s[3].Time)
+	a.Equal(first.Add(2005*time.Millisecond), *s[4].Time)
+	a.Equal(first.Add(3005*time.Millisecond), *s[5].Time)
+	a.Equal(first.Add(4005*time.Millisecond), *s[6].Time)
+	p.Flush()
+	a.Equal(first.Add(4005*time.Millisecond), *p.Log().Segments[7].Time)
+}
+
+func TestFillTimeAfterRedate(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	p.Location = time.UTC
+	_, err := p.Parse(bytes.NewBufferString(`[UnityCrossThreadLogger]05/04/2019 10:00:00
+{}
+[Client GRE]GREConnection.ProcessMessage
+{}
+[UnityCrossThreadLogger]07/04/2019 10:00:00
+{}
+[Client GRE]GREConnection.ProcessMessage
+{}
+[UnityCrossThreadLogger]13/04/2019 10:00:00
+{}
+`))
+	a.Nil(err)
+	p.Flush()
+	s := p.Log().Segments[1:]
+	a.Equal(utc(2019, 4, 5, 10, 0, 0), *s[0].Time)
+	// Was between 4 May and 4 July while the month was thought to come first
+	a.Equal(utc(2019, 4, 6, 10, 0, 0), *s[1].Time)
+	a.Equal(utc(2019, 4, 7, 10, 0, 0), *s[2].Time)
+	a.Equal(utc(2019, 4, 10, 10, 0, 0), *s[3].Time)
+	a.Equal(utc(2019, 4, 13, 10, 0, 0), *s[4].Time)
+}
+
+func TestRedateHandedOutTimes(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	p.Location = time.UTC
+	x := NewExtractor()
+	segments, err := p.Parse(bytes.NewBufferString(`[UnityCrossThreadLogger]05/04/2019 10:00:00
+<== PlayerInventory.CrackBoostersV3(1)
+{"cardsOpened": []}
+[UnityCrossThreadLogger]07/04/2019 10:00:00
+{}
+`))
+	a.Nil(err)
+	x.Feed(segments)
+	opened := segments[1].Time
+	a.Equal(utc(2019, 5, 4, 10, 0, 0), *opened)
+	// The date order only becomes known after the booster was extracted
+	segments, err = p.Parse(bytes.NewBufferString(`[UnityCrossThreadLogger]13/04/2019 10:00:00
+{}
+`))
+	a.Nil(err)
+	x.Feed(segments)
+	a.Equal(utc(2019, 4, 5, 10, 0, 0), *opened)
+	boosters := x.Extraction().Boosters
+	a.Len(boosters, 1)
+	a.Equal(utc(2019, 4, 5, 10, 0, 0), boosters[0].OpenedAt)
+}
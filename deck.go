@@ -103,7 +103,7 @@ func getCards(cards interface{}) []ArenaDeckCard {
 
 // IsArenaDecks checks if a segment contains Arena Decks
 func (s *Segment) IsArenaDecks() bool {
-	return s.SegmentType == DeckGetDeckLists
+	return s.Is(DeckGetDeckLists)
 }
 
 // ParseArenaDecks parses out arena decks from a segment if present.
@@ -111,6 +111,6 @@ func (s *Segment) IsArenaDecks() bool {
 // ArenaDecks by calling `IsArenaDecks()`
 func (s *Segment) ParseArenaDecks() ([]ArenaDeck, error) {
 	var decks []ArenaDeck
-	err := json.Unmarshal(stripNonJSON(s.Text), &decks)
+	err := s.decodeArray(&decks)
 	return decks, err
 }
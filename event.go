@@ -1,16 +1,13 @@
 package gathering
 
-import (
-	"encoding/json"
-)
-
 // ArenaEvent encapsulates the various stages a player may be in an event.
 // When the log is parsed, the player may have started an event, be in the middle
 // of an event, or just signed on to finish an event.
 // The server will use the ID to track individual events.
 type ArenaEvent struct {
-	ClaimPrize *ArenaEventClaimPrize `json:"claimPrize"`
-	Prize      *ArenaInventoryUpdate `json:"prize"`
+	ClaimPrizeRequest *ArenaEventClaimPrizeRequest `json:"claimPrizeRequest"`
+	ClaimPrize        *ArenaEventClaimPrize        `json:"claimPrize"`
+	Prize             *ArenaInventoryUpdate        `json:"prize"`
 }
 
 // ArenaEventJoin is the payload when a user joins an event
@@ -42,7 +39,7 @@ type ArenaEventClaimPrizeRequestParams struct {
 // ArenaEventClaimPrizeRequest is the inventory update from an event to know what the
 // prizes are.
 type ArenaEventClaimPrizeRequest struct {
-	params *ArenaEventClaimPrizeRequestParams
+	Params *ArenaEventClaimPrizeRequestParams `json:"params"`
 }
 
 // ArenaEventClaimPrizeModuleInstanceData has the data in claim prize
@@ -108,17 +105,17 @@ type ArenaEventGetPlayerCourse struct {
 
 // IsEventJoin checks if a segment contains an Event Join
 func (s *Segment) IsEventJoin() bool {
-	return s.SegmentType == EventJoin
+	return s.Is(EventJoin)
 }
 
 // IsEventGetPlayerCourse does this segment contain the player course
 func (s *Segment) IsEventGetPlayerCourse() bool {
-	return s.SegmentType == EventGetPlayerCourse
+	return s.Is(EventGetPlayerCourse)
 }
 
 // IsEventDeckSubmit does this segment contain a deck submit for the play queue
 func (s *Segment) IsEventDeckSubmit() bool {
-	return s.SegmentType == EventDeckSubmit
+	return s.Is(EventDeckSubmit)
 }
 
 // JoinedEvent is a higher level function to find if you joined
@@ -129,20 +126,20 @@ func (s *Segment) JoinedEvent() bool {
 
 // IsClaimPrize checks if this segment claims a prize
 func (s *Segment) IsClaimPrize() bool {
-	return s.SegmentType == EventClaimPrize
+	return s.Is(EventClaimPrize)
 }
 
 // ParseEventJoin parses out an event from JSON
 func (s *Segment) ParseEventJoin() (*ArenaEventJoin, error) {
 	var join ArenaEventJoin
-	err := json.Unmarshal(stripNonJSON(s.Text), &join)
+	err := s.decodeObject(&join)
 	return &join, err
 }
 
 // ParseEventPayEntry parses out a pay entry value
 func (s *Segment) ParseEventPayEntry() (*ArenaEventPayEntry, error) {
 	var pay ArenaEventPayEntry
-	err := json.Unmarshal(stripNonJSON(s.Text), &pay)
+	err := s.decodeObject(&pay)
 	return &pay, err
 
 }
@@ -151,13 +148,21 @@ func (s *Segment) ParseEventPayEntry() (*ArenaEventPayEntry, error) {
 // to verify the deck the player is using going into a game.
 func (s *Segment) ParseJoinedEvent() (*ArenaEventGetPlayerCourse, error) {
 	var course ArenaEventGetPlayerCourse
-	err := json.Unmarshal(stripNonJSON(s.Text), &course)
+	err := s.decodeObject(&course)
 	return &course, err
 }
 
+// ParseEventClaimPrizeRequest parses the request the client sent to claim a
+// prize. This is the request half of an Event.ClaimPrize Call.
+func (s *Segment) ParseEventClaimPrizeRequest() (*ArenaEventClaimPrizeRequest, error) {
+	var req ArenaEventClaimPrizeRequest
+	err := s.decodeObject(&req)
+	return &req, err
+}
+
 // ParseEventClaimPrize parses an event claim prize
 func (s *Segment) ParseEventClaimPrize() (*ArenaEventClaimPrize, error) {
 	var prize ArenaEventClaimPrize
-	err := json.Unmarshal(stripNonJSON(s.Text), &prize)
+	err := s.decodeObject(&prize)
 	return &prize, err
 }
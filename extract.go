@@ -0,0 +1,560 @@
+package gathering
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The entities an ExtractError can be for. They match the UploadData fields.
+const (
+	EntityCollection = "collection"
+	EntityRank       = "rank"
+	EntityInventory  = "inventory"
+	EntityAuth       = "auth"
+	EntityDecks      = "decks"
+	EntityBoosters   = "boosters"
+	EntityMatches    = "matches"
+	EntityEvents     = "events"
+)
+
+// ExtractError is a problem finding one kind of data in the log. Segment is
+// the segment that couldn't be parsed, or nil if the data wasn't found at all.
+type ExtractError struct {
+	Entity  string
+	Segment *Segment
+	Err     error
+}
+
+func (e *ExtractError) Error() string {
+	if e.Segment != nil {
+		return fmt.Sprintf("%v at lines %v: %v", e.Entity, e.Segment.Range, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Entity, e.Err)
+}
+
+// Extraction is everything found in a log. Errors holds every problem hit
+// along the way, a missing or broken entity doesn't stop the others.
+type Extraction struct {
+	UploadData
+	Errors []*ExtractError
+}
+
+// extractor collects one kind of data while the log is walked
+type extractor interface {
+	visit(i int, s *Segment)
+}
+
+// walk feeds every segment to the extractors in a single pass
+func (l *Log) walk(extractors ...extractor) {
+	for i, s := range l.Segments {
+		for _, e := range extractors {
+			e.visit(i, s)
+		}
+	}
+}
+
+// Extract finds all the data in the log in one pass over the segments
+func (l *Log) Extract() *Extraction {
+	x := NewExtractor()
+	x.Feed(l.Segments)
+	return x.Extraction()
+}
+
+// Extractor finds the data in a log as it is parsed. Feed it the segments a
+// LogParser returns, in order, and take an Extraction whenever it is needed;
+// each segment is only decoded once.
+type Extractor struct {
+	collection *collectionExtractor
+	rank       *rankExtractor
+	inventory  *inventoryExtractor
+	auth       *authExtractor
+	decks      *decksExtractor
+	boosters   *boostersExtractor
+	matches    *matchExtractor
+	events     *eventExtractor
+	next       int
+}
+
+// NewExtractor creates an Extractor that hasn't seen any segments
+func NewExtractor() *Extractor {
+	x := &Extractor{}
+	x.Reset()
+	return x
+}
+
+// Reset forgets everything, for when the log starts over
+func (x *Extractor) Reset() {
+	x.collection = &collectionExtractor{}
+	x.rank = &rankExtractor{}
+	x.inventory = &inventoryExtractor{}
+	x.auth = &authExtractor{}
+	x.decks = &decksExtractor{}
+	x.boosters = &boostersExtractor{}
+	x.matches = newMatchExtractor()
+	x.events = newEventExtractor()
+	x.next = 0
+}
+
+// Feed walks the segments that follow the ones already fed
+func (x *Extractor) Feed(segments []*Segment) {
+	extractors := []extractor{x.collection, x.rank, x.inventory, x.auth, x.decks, x.boosters, x.matches, x.events}
+	for _, s := range segments {
+		for _, e := range extractors {
+			e.visit(x.next, s)
+		}
+		x.next++
+	}
+}
+
+// Trim forgets the boosters, prizes, problems and finished matches found so
+// far, once they have been dealt with, so an Extractor that runs for a whole
+// session only holds what Kept needs. Later Extractions only have what is
+// found after, and the match being played.
+func (x *Extractor) Trim() {
+	x.boosters.boosters = nil
+	x.boosters.segments = nil
+	x.boosters.problems = nil
+	x.events.events = make([]*ArenaEvent, 0)
+	x.events.problems = nil
+	x.rank.problems = nil
+	m := x.matches
+	m.problems = nil
+	var ids []string
+	for _, id := range m.ids {
+		if match := m.matches[id]; match != nil && match == m.match {
+			ids = append(ids, id)
+			continue
+		}
+		delete(m.matches, id)
+	}
+	m.ids = ids
+}
+
+// Kept are the segments an Extractor needs to carry on from where this one
+// is: the latest collection, inventory and decks, the player name, the rank
+// and the updates since, the deck last joined with, the match being played
+// and prize claims waiting for their response. See Checkpoint.Keep.
+func (x *Extractor) Kept() []*Segment {
+	var kept []*Segment
+	kept = append(kept, x.collection.last, x.inventory.last, x.auth.first, x.decks.last)
+	kept = append(kept, x.rank.segments...)
+	kept = append(kept, x.matches.kept()...)
+	kept = append(kept, x.events.kept(x.next)...)
+	seen := make(map[*Segment]bool)
+	var segments []*Segment
+	for _, s := range kept {
+		if s != nil && !seen[s] {
+			seen[s] = true
+			segments = append(segments, s)
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Range[0] < segments[j].Range[0]
+	})
+	return segments
+}
+
+// Extraction is everything found in the segments fed so far
+func (x *Extractor) Extraction() *Extraction {
+	e := &Extraction{}
+	var err error
+	e.Collection, err = x.collection.result()
+	e.add(EntityCollection, err)
+	e.Rank, err = x.rank.result()
+	e.add(EntityRank, err)
+	e.Inventory, err = x.inventory.result()
+	e.add(EntityInventory, err)
+	name, err := x.auth.result()
+	e.add(EntityAuth, err)
+	if err == nil {
+		e.Auth = &ArenaAuthRequest{
+			Payload: ArenaAuthRequestPayload{
+				PlayerName: string(name),
+			},
+		}
+	}
+	e.Decks, err = x.decks.result()
+	e.add(EntityDecks, err)
+	e.Boosters = x.boosters.result()
+	e.Matches = x.matches.result()
+	e.Events = x.events.result()
+	e.Errors = append(e.Errors, x.rank.problems...)
+	e.Errors = append(e.Errors, x.boosters.problems...)
+	e.Errors = append(e.Errors, x.matches.problems...)
+	e.Errors = append(e.Errors, x.events.problems...)
+	return e
+}
+
+func (x *Extraction) add(entity string, err error) {
+	if err == nil {
+		return
+	}
+	if e, ok := err.(*ExtractError); ok {
+		x.Errors = append(x.Errors, e)
+		return
+	}
+	x.Errors = append(x.Errors, &ExtractError{Entity: entity, Err: err})
+}
+
+// collectionExtractor keeps the latest collection
+type collectionExtractor struct {
+	last *Segment
+}
+
+func (c *collectionExtractor) visit(i int, s *Segment) {
+	if s.IsCollection() {
+		c.last = s
+	}
+}
+
+func (c *collectionExtractor) result() (map[string]int, error) {
+	if c.last == nil {
+		return nil, ErrNotFound
+	}
+	col, err := c.last.ParseCollection()
+	if err != nil {
+		return col, &ExtractError{Entity: EntityCollection, Segment: c.last, Err: err}
+	}
+	return col, nil
+}
+
+// rankExtractor applies rank updates to the last full rank info
+type rankExtractor struct {
+	rank     *ArenaRankInfo
+	segments []*Segment
+	err      error
+	problems []*ExtractError
+}
+
+func (r *rankExtractor) visit(i int, s *Segment) {
+	if r.err != nil {
+		return
+	}
+	if s.IsRankInfo() {
+		rank, err := s.ParseRankInfo()
+		r.rank = rank
+		r.segments = []*Segment{s}
+		if err != nil {
+			r.err = &ExtractError{Entity: EntityRank, Segment: s, Err: err}
+			return
+		}
+	}
+	if s.IsRankUpdated() && r.rank != nil {
+		updated, err := s.ParseRankUpdated()
+		if err != nil {
+			r.problems = append(r.problems, &ExtractError{Entity: EntityRank, Segment: s, Err: err})
+			return
+		}
+		r.rank.Update(updated)
+		r.segments = append(r.segments, s)
+	}
+}
+
+func (r *rankExtractor) result() (*ArenaRankInfo, error) {
+	return r.rank, r.err
+}
+
+// inventoryExtractor keeps the latest inventory
+type inventoryExtractor struct {
+	last *Segment
+}
+
+func (v *inventoryExtractor) visit(i int, s *Segment) {
+	if s.IsPlayerInventory() {
+		v.last = s
+	}
+}
+
+func (v *inventoryExtractor) result() (*ArenaPlayerInventory, error) {
+	if v.last == nil {
+		return nil, ErrNotFound
+	}
+	inv, err := v.last.ParsePlayerInventory()
+	if err != nil {
+		return inv, &ExtractError{Entity: EntityInventory, Segment: v.last, Err: err}
+	}
+	return inv, nil
+}
+
+// authExtractor keeps the first player name
+type authExtractor struct {
+	first *Segment
+}
+
+func (a *authExtractor) visit(i int, s *Segment) {
+	if a.first == nil && s.IsPlayerAuth() {
+		a.first = s
+	}
+}
+
+func (a *authExtractor) result() ([]byte, error) {
+	if a.first == nil {
+		return nil, ErrNotFound
+	}
+	return a.first.ParseAuth()
+}
+
+// decksExtractor keeps the latest deck lists
+type decksExtractor struct {
+	last *Segment
+}
+
+func (d *decksExtractor) visit(i int, s *Segment) {
+	if s.IsArenaDecks() {
+		d.last = s
+	}
+}
+
+func (d *decksExtractor) result() ([]ArenaDeck, error) {
+	if d.last == nil {
+		return nil, ErrNotFound
+	}
+	decks, err := d.last.ParseArenaDecks()
+	if err != nil {
+		return decks, &ExtractError{Entity: EntityDecks, Segment: d.last, Err: err}
+	}
+	return decks, nil
+}
+
+// boostersExtractor collects every opened booster. The time they were
+// opened is taken when the result is, since it may change once the log's
+// date order is known.
+type boostersExtractor struct {
+	boosters []*Booster
+	segments []*Segment
+	problems []*ExtractError
+}
+
+func (b *boostersExtractor) visit(i int, s *Segment) {
+	if !s.IsCrackBooster() {
+		return
+	}
+	booster, err := s.ParseCrackBooster()
+	if err != nil {
+		b.problems = append(b.problems, &ExtractError{Entity: EntityBoosters, Segment: s, Err: err})
+		return
+	}
+	b.boosters = append(b.boosters, booster)
+	b.segments = append(b.segments, s)
+}
+
+func (b *boostersExtractor) result() []*Booster {
+	if b.boosters == nil {
+		return make([]*Booster, 0)
+	}
+	for i, booster := range b.boosters {
+		if t := b.segments[i].Time; t != nil {
+			booster.OpenedAt = *t
+		}
+	}
+	return b.boosters
+}
+
+// eventExtractor pairs prize claims with the request that made them and the
+// inventory update that paid them out
+type eventExtractor struct {
+	events   []*ArenaEvent
+	calls    *callPairer
+	update   *Segment
+	updateAt int
+	problems []*ExtractError
+}
+
+func newEventExtractor() *eventExtractor {
+	return &eventExtractor{
+		events:   make([]*ArenaEvent, 0),
+		calls:    newCallPairer(),
+		updateAt: -1,
+	}
+}
+
+func (e *eventExtractor) visit(i int, s *Segment) {
+	call, _ := e.calls.add(s)
+	if s.IsInventoryUpdate() {
+		e.update = s
+		e.updateAt = i
+	}
+	if !s.IsClaimPrize() {
+		return
+	}
+	claim, err := s.ParseEventClaimPrize()
+	if err != nil {
+		e.problems = append(e.problems, &ExtractError{Entity: EntityEvents, Segment: s, Err: err})
+		return
+	}
+	event := &ArenaEvent{
+		ClaimPrize: claim,
+	}
+	if call != nil && call.RequestSegment != nil {
+		if req, err := call.RequestSegment.ParseEventClaimPrizeRequest(); err == nil {
+			event.ClaimPrizeRequest = req
+		}
+	}
+	// The inventory change comes a few segments before the prize
+	if e.update != nil && i-e.updateAt < 10 {
+		update, err := e.update.ParseInventoryUpdate()
+		if err == nil {
+			event.Prize = update
+		}
+	}
+	e.events = append(e.events, event)
+}
+
+// kept are the prize claims still waiting for their response, and the
+// inventory update if it is recent enough to be their prize. next is the
+// index of the next segment.
+func (e *eventExtractor) kept(next int) []*Segment {
+	var kept []*Segment
+	if e.update != nil && next-e.updateAt < 10 {
+		kept = append(kept, e.update)
+	}
+	for _, pending := range e.calls.pending {
+		if pending.call.Method == "Event.ClaimPrize" {
+			kept = append(kept, pending.call.RequestSegment)
+		}
+	}
+	return kept
+}
+
+func (e *eventExtractor) result() []*ArenaEvent {
+	return e.events
+}
+
+// matchExtractor follows matches from their start, through the game events,
+// to their end. The deck is the one the player last joined a queue with.
+type matchExtractor struct {
+	matches  map[string]*ArenaMatch
+	ids      []string
+	match    *ArenaMatch
+	joined   *Segment
+	started  []*Segment
+	done     bool
+	problems []*ExtractError
+}
+
+func newMatchExtractor() *matchExtractor {
+	return &matchExtractor{
+		matches: make(map[string]*ArenaMatch),
+	}
+}
+
+func (m *matchExtractor) problem(s *Segment, err error) {
+	m.problems = append(m.problems, &ExtractError{Entity: EntityMatches, Segment: s, Err: err})
+}
+
+func (m *matchExtractor) deck() (*ArenaDeck, error) {
+	if m.joined == nil {
+		return nil, ErrNotFound
+	}
+	course, err := m.joined.ParseJoinedEvent()
+	if err != nil {
+		return nil, &ExtractError{Entity: EntityMatches, Segment: m.joined, Err: err}
+	}
+	return course.CourseDeck, nil
+}
+
+func (m *matchExtractor) visit(i int, s *Segment) {
+	if m.done {
+		return
+	}
+	if s.JoinedEvent() {
+		m.joined = s
+	}
+	if s.IsMatchStart() {
+		m.started = []*Segment{m.joined, s}
+		match, err := s.ParseMatchStart()
+		match.Games = append(match.Games, &ArenaGame{
+			GameStart: match.GameStart,
+		})
+		m.match = match
+		if err != nil {
+			m.problem(s, err)
+			return
+		}
+		deck, err := m.deck()
+		if err != nil {
+			m.problem(s, err)
+			return
+		}
+		match.CourseDeck = deck
+		match.setDeck(match.Games[0], deck)
+		if _, ok := m.matches[match.MatchID]; !ok {
+			m.ids = append(m.ids, match.MatchID)
+		}
+		m.matches[match.MatchID] = match
+	}
+	// same as normal, but the logs go into the current game
+	if m.match != nil && s.IsMatchEvent() {
+		m.started = append(m.started, s)
+		event, err := s.ParseMatchEvent()
+		if err != nil {
+			m.problem(s, err)
+			return
+		}
+		m.match.LogMatchEvent(event)
+	}
+	if m.match != nil && s.IsClientToGRE() {
+		m.started = append(m.started, s)
+		msg, err := s.ParseClientToGRE()
+		if err != nil {
+			m.problem(s, err)
+			return
+		}
+		m.match.LogClientMessage(msg, s.Time)
+	}
+	if m.match != nil && s.IsSideboardStop() {
+		m.started = append(m.started, s)
+		m.match.NextGame(s.Time)
+	}
+	if s.IsMatchEnd() {
+		end, err := s.ParseMatchEnd()
+		if err == nil && (end.Params == nil || end.Params.PayloadObject == nil || end.Params.PayloadObject.MatchID == nil) {
+			err = ErrNotFound
+		}
+		if err != nil {
+			m.problem(s, err)
+			m.done = true
+			return
+		}
+		p := end.Params.PayloadObject
+		m.match = m.matches[*p.MatchID]
+		if m.match == nil {
+			// We are missing the first part of the match.
+			// Get what we can and let the server figure out the rest.
+			return
+		}
+		m.started = append(m.started, s)
+		m.match.UpdateGameEnd(p)
+	}
+	if m.match != nil && s.IsMatchCompleted() {
+		end, err := s.ParseMatchCompleted()
+		if err != nil {
+			m.problem(s, err)
+			m.done = true
+			return
+		}
+		m.match.UpdateMatchCompleted(end)
+		m.match = nil
+		m.started = nil
+	}
+}
+
+// kept are the deck last joined with, and the match being played from its
+// start with the deck it was joined with
+func (m *matchExtractor) kept() []*Segment {
+	kept := []*Segment{m.joined}
+	if m.match != nil {
+		kept = append(kept, m.started...)
+	}
+	return kept
+}
+
+func (m *matchExtractor) result() []*ArenaMatch {
+	var found []*ArenaMatch
+	for _, id := range m.ids {
+		match := m.matches[id]
+		match.OpponentDeck = match.InferOpponentDeck()
+		found = append(found, match)
+	}
+	return found
+}
@@ -0,0 +1,136 @@
+package gathering
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractMatchesSeparateCalls(t *testing.T) {
+	a := assert.New(t)
+	f, err := os.Open("test/march-constructed.txt")
+	a.Nil(err)
+	alog, err := ParseLog(f)
+	a.Nil(err)
+	x := alog.Extract()
+	col, err := alog.Collection()
+	a.Nil(err)
+	a.Equal(col, x.Collection)
+	inv, err := alog.Inventory()
+	a.Nil(err)
+	a.Equal(inv, x.Inventory)
+	decks, err := alog.Decks()
+	a.Nil(err)
+	a.Equal(decks, x.Decks)
+	events, err := alog.Events()
+	a.Nil(err)
+	a.Equal(events, x.Events)
+	a.Len(x.Events, 1)
+	a.Equal(500, x.Events[0].Prize.Delta.GoldDelta)
+	// There's no auth in this log, it is reported but doesn't stop the rest
+	a.Nil(x.Auth)
+	a.Len(x.Errors, 1)
+	a.Equal(EntityAuth, x.Errors[0].Entity)
+	a.Equal(ErrNotFound, x.Errors[0].Err)
+}
+
+func TestExtractErrors(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	_, err := p.Parse(bytes.NewBufferString(`[UnityCrossThreadLogger]4/2/2019 3:01:48 PM
+<== PlayerInventory.CrackBoostersV3(276)
+{"cardsOpened": "not a list"}
+[UnityCrossThreadLogger]4/2/2019 3:01:49 PM
+<== PlayerInventory.CrackBoostersV3(277)
+{"cardsOpened": [{"grpId": 69167}]}
+[UnityCrossThreadLogger]4/2/2019 3:01:50 PM
+<== Deck.GetDeckListsV3(278)
+{"not": "decks"}
+`))
+	a.Nil(err)
+	p.Flush()
+	x := p.Log().Extract()
+	a.Len(x.Boosters, 1)
+	a.Equal(69167, x.Boosters[0].CardsOpened[0].GrpID)
+	errs := make(map[string]*ExtractError)
+	for _, e := range x.Errors {
+		errs[e.Entity] = e
+	}
+	a.Equal([]int{1, 4}, errs[EntityBoosters].Segment.Range)
+	a.Equal([]int{7, 10}, errs[EntityDecks].Segment.Range)
+	a.Equal(ErrNotFound, errs[EntityCollection].Err)
+	a.Nil(errs[EntityRank])
+}
+
+func TestExtractorResume(t *testing.T) {
+	a := assert.New(t)
+	l := &bo3Log{}
+	l.segment(`<== PlayerInventory.GetPlayerCardsV3(1)
+{"60001": 4}`)
+	l.segment(`<== Event.DeckSubmitV3(2)
+{"CourseDeck": {"id": "deck1", "mainDeck": [{"id": 60001, "quantity": 4}]}}`)
+	l.segment(` (Incoming Event.MatchCreated)
+{"matchId": "m1", "opponentScreenName": "Opponent"}`)
+	l.gre(1, 1, "MatchState_GameInProgress", "")
+	l.gameStop(1, 2)
+	half := l.Len()
+	l.gre(2, 10, "MatchState_GameInProgress", "")
+	l.gameStop(2, 1)
+	l.segment(`{"matchGameRoomStateChangedEvent": {"gameRoomInfo": {"stateType": "MatchGameRoomStateType_MatchCompleted",
+ "finalMatchResult": {"matchId": "m1", "resultList": [
+ {"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 2},
+ {"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1},
+ {"scope": "MatchScope_Match", "result": "ResultType_WinLoss", "winningTeamId": 1}]}}}}`)
+	b := l.Bytes()
+
+	first := NewLogParser(nil)
+	segments, err := first.Parse(bytes.NewReader(b[:half]))
+	a.Nil(err)
+	x := NewExtractor()
+	x.Feed(segments)
+	cp := first.Checkpoint()
+	cp.Keep(x.Kept())
+	data, err := json.Marshal(cp)
+	a.Nil(err)
+	var saved Checkpoint
+	a.Nil(json.Unmarshal(data, &saved))
+	a.Len(saved.Segments, 4)
+
+	second := NewLogParser(&saved)
+	resumed := NewExtractor()
+	resumed.Feed(second.Log().Segments)
+	segments, err = second.Parse(bytes.NewReader(b[saved.Offset:]))
+	a.Nil(err)
+	resumed.Feed(segments)
+	resumed.Feed([]*Segment{second.Flush()})
+
+	// The same as extracting the whole log at once
+	whole := NewLogParser(nil)
+	_, err = whole.Parse(bytes.NewReader(b))
+	a.Nil(err)
+	whole.Flush()
+	expected := whole.Log().Extract()
+	got := resumed.Extraction()
+	a.Equal(expected.Collection, got.Collection)
+	a.Len(got.Matches, 1)
+	m := got.Matches[0]
+	a.Equal("deck1", m.CourseDeck.ID)
+	a.Len(m.Games, 2)
+	a.Equal(ResultWin, m.Result)
+	a.Equal("1-1", m.Score())
+	a.Equal(expected.Matches[0].Result, m.Result)
+
+	// Once the match is over only what it was joined with is kept
+	cp = second.Checkpoint()
+	cp.Keep(resumed.Kept())
+	a.Len(cp.Segments, 2)
+
+	// Trimming drops the finished match but not the collection
+	resumed.Trim()
+	got = resumed.Extraction()
+	a.Len(got.Matches, 0)
+	a.Equal(expected.Collection, got.Collection)
+}
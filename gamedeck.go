@@ -0,0 +1,74 @@
+package gathering
+
+// ArenaDeckDiff is what was sideboarded in and out of a deck, compared to the
+// deck of the first game
+type ArenaDeckDiff struct {
+	In  []ArenaDeckCard `json:"in"`
+	Out []ArenaDeckCard `json:"out"`
+}
+
+func cardCounts(cards []ArenaDeckCard) map[int]int {
+	counts := make(map[int]int)
+	for _, c := range cards {
+		counts[c.ID] += c.Quantity
+	}
+	return counts
+}
+
+// addedCards returns the cards there are more of in to than in from, in the
+// order of to
+func addedCards(from, to []ArenaDeckCard) []ArenaDeckCard {
+	before := cardCounts(from)
+	after := cardCounts(to)
+	added := []ArenaDeckCard{}
+	seen := make(map[int]bool)
+	for _, c := range to {
+		if n := after[c.ID] - before[c.ID]; n > 0 && !seen[c.ID] {
+			added = append(added, ArenaDeckCard{ID: c.ID, Quantity: n})
+		}
+		seen[c.ID] = true
+	}
+	return added
+}
+
+// diffDecks compares the main decks of from and to
+func diffDecks(from, to *ArenaDeck) *ArenaDeckDiff {
+	return &ArenaDeckDiff{
+		In:  addedCards(from.MainDeck, to.MainDeck),
+		Out: addedCards(to.MainDeck, from.MainDeck),
+	}
+}
+
+// setDeck sets the deck a game was played with. Decks from the GRE only have
+// cards, so they keep the id and name of the deck the match started with.
+func (a *ArenaMatch) setDeck(game *ArenaGame, deck *ArenaDeck) {
+	if deck == nil {
+		return
+	}
+	if a.CourseDeck != nil && deck.ID == "" {
+		deck.ID = a.CourseDeck.ID
+		deck.Name = a.CourseDeck.Name
+		deck.Format = a.CourseDeck.Format
+	}
+	game.CourseDeck = deck
+	a.updateSideboarding()
+}
+
+// SubmitDeck sets the deck for the next game, after sideboarding
+func (a *ArenaMatch) SubmitDeck(deck *ArenaDeck) {
+	a.setDeck(a.game(a.nextGameNumber()), deck)
+}
+
+// updateSideboarding compares the deck of every game after the first to the
+// first
+func (a *ArenaMatch) updateSideboarding() {
+	if len(a.Games) == 0 || a.Games[0].CourseDeck == nil {
+		return
+	}
+	first := a.Games[0].CourseDeck
+	for _, g := range a.Games[1:] {
+		if g.CourseDeck != nil {
+			g.SideboardDiff = diffDecks(first, g.CourseDeck)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package gathering
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDecks(t *testing.T) {
+	a := assert.New(t)
+	from := &ArenaDeck{MainDeck: []ArenaDeckCard{{ID: 1, Quantity: 4}, {ID: 2, Quantity: 2}}}
+	to := &ArenaDeck{MainDeck: []ArenaDeckCard{{ID: 1, Quantity: 2}, {ID: 3, Quantity: 2}, {ID: 2, Quantity: 2}}}
+	diff := diffDecks(from, to)
+	a.Equal([]ArenaDeckCard{{ID: 3, Quantity: 2}}, diff.In)
+	a.Equal([]ArenaDeckCard{{ID: 1, Quantity: 2}}, diff.Out)
+}
@@ -0,0 +1,258 @@
+package gathering
+
+import (
+	"sort"
+)
+
+// The GameStateMessage types. A full state replaces everything we know, a diff
+// only has what changed since PrevGameStateID.
+const (
+	GameStateFull = "GameStateType_Full"
+	GameStateDiff = "GameStateType_Diff"
+)
+
+// The GRE message types that carry a GameStateMessage
+const (
+	GREGameStateMessage       = "GREMessageType_GameStateMessage"
+	GREQueuedGameStateMessage = "GREMessageType_QueuedGameStateMessage"
+)
+
+// Zone is an area of the game, like a player's hand or the battlefield.
+// ObjectInstanceIDs are the objects in it, in order.
+type Zone struct {
+	ZoneID            int    `json:"zoneId"`
+	Type              string `json:"type"`
+	Visibility        string `json:"visibility"`
+	OwnerSeatID       int    `json:"ownerSeatId"`
+	ObjectInstanceIDs []int  `json:"objectInstanceIds"`
+}
+
+// GameSnapshot is the state of a game after a GameStateMessage was applied.
+// Players are keyed by their seat, Zones and Objects by their ids.
+type GameSnapshot struct {
+	ID       int                          `json:"gameStateId"`
+	GameInfo *GameInfo                    `json:"gameInfo"`
+	TurnInfo *TurnInfo                    `json:"turnInfo"`
+	Players  map[int]PlayerState          `json:"players"`
+	Zones    map[int]Zone                 `json:"zones"`
+	Objects  map[int]ArenaMatchGameObject `json:"objects"`
+}
+
+func newGameSnapshot() *GameSnapshot {
+	return &GameSnapshot{
+		Players: make(map[int]PlayerState),
+		Zones:   make(map[int]Zone),
+		Objects: make(map[int]ArenaMatchGameObject),
+	}
+}
+
+// copy makes a snapshot that can be changed without changing this one
+func (g *GameSnapshot) copy() *GameSnapshot {
+	c := newGameSnapshot()
+	c.ID = g.ID
+	c.GameInfo = g.GameInfo
+	c.TurnInfo = g.TurnInfo
+	for k, v := range g.Players {
+		c.Players[k] = v
+	}
+	for k, v := range g.Zones {
+		c.Zones[k] = v
+	}
+	for k, v := range g.Objects {
+		c.Objects[k] = v
+	}
+	return c
+}
+
+// Turn is the turn number, or 0 before the game has started
+func (g *GameSnapshot) Turn() int {
+	if g.TurnInfo == nil {
+		return 0
+	}
+	return g.TurnInfo.TurnNumber
+}
+
+// Life is the life total of the player in the seat
+func (g *GameSnapshot) Life(seat int) int {
+	return g.Players[seat].LifeTotal
+}
+
+// ZonesOfType returns the zones of a type, like `ZoneType_Hand`, ordered by
+// id.
+func (g *GameSnapshot) ZonesOfType(zoneType string) []Zone {
+	var zones []Zone
+	for _, z := range g.Zones {
+		if z.Type == zoneType {
+			zones = append(zones, z)
+		}
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].ZoneID < zones[j].ZoneID
+	})
+	return zones
+}
+
+// InZone returns the objects in all zones of a type. Pass a seat to only get
+// the zones that player owns, or 0 for all of them. Objects the GRE hasn't
+// described to us (like cards in the opponent's library) are left out.
+func (g *GameSnapshot) InZone(zoneType string, seat int) []ArenaMatchGameObject {
+	var objects []ArenaMatchGameObject
+	for _, z := range g.ZonesOfType(zoneType) {
+		if seat != 0 && z.OwnerSeatID != seat {
+			continue
+		}
+		for _, id := range z.ObjectInstanceIDs {
+			if o, ok := g.Objects[id]; ok {
+				objects = append(objects, o)
+			}
+		}
+	}
+	return objects
+}
+
+// Battlefield returns the objects on the battlefield
+func (g *GameSnapshot) Battlefield() []ArenaMatchGameObject {
+	return g.InZone("ZoneType_Battlefield", 0)
+}
+
+// apply updates the snapshot with a message. Everything in a diff replaces
+// what we had with the same id.
+func (g *GameSnapshot) apply(msg *GameStateMessage) {
+	g.ID = msg.GameStateID
+	if msg.GameInfo != nil {
+		g.GameInfo = msg.GameInfo
+	}
+	if msg.TurnInfo != nil {
+		g.TurnInfo = msg.TurnInfo
+	}
+	for _, p := range msg.Players {
+		g.Players[p.SystemSeatNumber] = p
+	}
+	for _, z := range msg.Zones {
+		g.Zones[z.ZoneID] = z
+	}
+	for _, o := range msg.GameObjects {
+		g.Objects[o.InstanceID] = o
+	}
+	for _, id := range msg.DiffDeletedInstanceIDs {
+		delete(g.Objects, id)
+	}
+}
+
+// keyframeEvery is the most diffs applied in a row without keeping a full
+// snapshot, which bounds the work At does to rebuild a state
+const keyframeEvery = 32
+
+// appliedState is a message as it was applied. base is the position of the
+// state it was applied to, or -1 when it started from nothing. Keyframes keep
+// the whole snapshot so the states after them can be rebuilt.
+type appliedState struct {
+	msg      *GameStateMessage
+	base     int
+	turn     int
+	keyframe *GameSnapshot
+}
+
+// GameState rebuilds a game from the GameStateMessages the GRE sends. Apply
+// the messages in the order they are logged, then look up the state at any
+// point with At or AtTurn. Only the messages are kept, with a full snapshot
+// at each turn and every few states, and older states are rebuilt from them.
+type GameState struct {
+	current *GameSnapshot
+	applied []*appliedState
+	byID    map[int]int
+	ids     []int
+	diffs   int
+}
+
+// NewGameState creates an empty game
+func NewGameState() *GameState {
+	return &GameState{
+		current: newGameSnapshot(),
+		byID:    make(map[int]int),
+	}
+}
+
+// Apply applies a GameStateMessage. A diff is applied to the state it was
+// made from when we have it, otherwise to the latest state.
+func (g *GameState) Apply(msg *GameStateMessage) *GameSnapshot {
+	saved := *msg
+	applied := &appliedState{msg: &saved, base: -1}
+	var next *GameSnapshot
+	prev, ok := g.byID[msg.PrevGameStateID]
+	switch {
+	case msg.Type == GameStateFull:
+		next = newGameSnapshot()
+	case msg.PrevGameStateID != 0 && ok:
+		applied.base = prev
+		if prev == len(g.applied)-1 {
+			next = g.current.copy()
+		} else {
+			next = g.rebuild(prev)
+		}
+	default:
+		applied.base = len(g.applied) - 1
+		next = g.current.copy()
+	}
+	next.apply(msg)
+	applied.turn = next.Turn()
+	g.diffs++
+	if applied.base < 0 || g.diffs >= keyframeEvery || applied.turn != g.current.Turn() {
+		applied.keyframe = next
+		g.diffs = 0
+	}
+	if _, ok := g.byID[next.ID]; !ok {
+		g.ids = append(g.ids, next.ID)
+	}
+	g.byID[next.ID] = len(g.applied)
+	g.applied = append(g.applied, applied)
+	g.current = next
+	return next
+}
+
+// rebuild makes the state at a position by applying the messages since the
+// keyframe before it. Older states are copies that can be changed freely.
+func (g *GameState) rebuild(pos int) *GameSnapshot {
+	if pos == len(g.applied)-1 {
+		return g.current
+	}
+	var msgs []*GameStateMessage
+	for g.applied[pos].keyframe == nil {
+		msgs = append(msgs, g.applied[pos].msg)
+		pos = g.applied[pos].base
+	}
+	s := g.applied[pos].keyframe.copy()
+	for i := len(msgs) - 1; i >= 0; i-- {
+		s.apply(msgs[i])
+	}
+	return s
+}
+
+// Current is the latest state
+func (g *GameState) Current() *GameSnapshot {
+	return g.current
+}
+
+// At returns the state with the gameStateId, or nil if we never saw it
+func (g *GameState) At(id int) *GameSnapshot {
+	pos, ok := g.byID[id]
+	if !ok {
+		return nil
+	}
+	return g.rebuild(pos)
+}
+
+// AtTurn returns the last state of a turn, or nil if the turn wasn't reached
+func (g *GameState) AtTurn(turn int) *GameSnapshot {
+	for pos := len(g.applied) - 1; pos >= 0; pos-- {
+		if g.applied[pos].turn == turn && g.byID[g.applied[pos].msg.GameStateID] == pos {
+			return g.rebuild(pos)
+		}
+	}
+	return nil
+}
+
+// IDs are the gameStateIds seen, in the order they were applied
+func (g *GameState) IDs() []int {
+	return g.ids
+}
@@ -0,0 +1,174 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGameStates = `[
+{"type": "GameStateType_Full", "gameStateId": 1,
+ "gameInfo": {"matchID": "m1", "gameNumber": 1},
+ "players": [{"lifeTotal": 20, "systemSeatNumber": 1}, {"lifeTotal": 20, "systemSeatNumber": 2}],
+ "zones": [
+  {"zoneId": 28, "type": "ZoneType_Battlefield"},
+  {"zoneId": 31, "type": "ZoneType_Hand", "ownerSeatId": 1, "objectInstanceIds": [160, 161]}
+ ],
+ "gameObjects": [
+  {"instanceId": 160, "grpId": 68739, "type": "GameObjectType_Card", "zoneId": 31, "ownerSeatId": 1},
+  {"instanceId": 161, "grpId": 68740, "type": "GameObjectType_Card", "zoneId": 31, "ownerSeatId": 1}
+ ]},
+{"type": "GameStateType_Diff", "gameStateId": 2, "prevGameStateId": 1,
+ "turnInfo": {"turnNumber": 1, "activePlayer": 1},
+ "zones": [
+  {"zoneId": 28, "type": "ZoneType_Battlefield", "objectInstanceIds": [162]},
+  {"zoneId": 31, "type": "ZoneType_Hand", "ownerSeatId": 1, "objectInstanceIds": [161]}
+ ],
+ "gameObjects": [
+  {"instanceId": 162, "grpId": 68739, "type": "GameObjectType_Card", "zoneId": 28, "ownerSeatId": 1}
+ ],
+ "diffDeletedInstanceIds": [160]},
+{"type": "GameStateType_Diff", "gameStateId": 3, "prevGameStateId": 2,
+ "turnInfo": {"turnNumber": 2, "activePlayer": 2},
+ "players": [{"lifeTotal": 17, "systemSeatNumber": 1}]}
+]`
+
+func testGameState(t *testing.T) *GameState {
+	var msgs []GameStateMessage
+	assert.Nil(t, json.Unmarshal([]byte(testGameStates), &msgs))
+	g := NewGameState()
+	for i := range msgs {
+		g.Apply(&msgs[i])
+	}
+	return g
+}
+
+func TestGameStateApply(t *testing.T) {
+	a := assert.New(t)
+	g := testGameState(t)
+	a.Equal([]int{1, 2, 3}, g.IDs())
+	cur := g.Current()
+	a.Equal(3, cur.ID)
+	a.Equal(2, cur.Turn())
+	a.Equal(17, cur.Life(1))
+	a.Equal(20, cur.Life(2))
+	a.Equal("m1", cur.GameInfo.MatchID)
+	a.Len(cur.Battlefield(), 1)
+	a.Equal(162, cur.Battlefield()[0].InstanceID)
+	hand := cur.InZone("ZoneType_Hand", 1)
+	a.Len(hand, 1)
+	a.Equal(161, hand[0].InstanceID)
+	a.Len(cur.InZone("ZoneType_Hand", 2), 0)
+	_, ok := cur.Objects[160]
+	a.False(ok)
+}
+
+func TestGameStateHistory(t *testing.T) {
+	a := assert.New(t)
+	g := testGameState(t)
+	first := g.At(1)
+	a.Len(first.Battlefield(), 0)
+	a.Len(first.InZone("ZoneType_Hand", 1), 2)
+	a.Equal(0, first.Turn())
+	a.Equal(20, g.At(2).Life(1))
+	a.Equal(2, g.AtTurn(1).ID)
+	a.Equal(3, g.AtTurn(2).ID)
+	a.Nil(g.AtTurn(5))
+	a.Nil(g.At(4))
+}
+
+func TestGameStateDiffFromOlderState(t *testing.T) {
+	a := assert.New(t)
+	g := testGameState(t)
+	// A diff made from state 1, not the latest
+	g.Apply(&GameStateMessage{
+		Type:            GameStateDiff,
+		GameStateID:     4,
+		PrevGameStateID: 1,
+		Players:         []PlayerState{{LifeTotal: 19, SystemSeatNumber: 2}},
+	})
+	cur := g.Current()
+	a.Equal(4, cur.ID)
+	a.Equal(20, cur.Life(1))
+	a.Equal(19, cur.Life(2))
+	a.Len(cur.Battlefield(), 0)
+}
+
+func TestGameStateFullResets(t *testing.T) {
+	a := assert.New(t)
+	g := testGameState(t)
+	g.Apply(&GameStateMessage{Type: GameStateFull, GameStateID: 10})
+	a.Len(g.Current().Objects, 0)
+	a.Len(g.At(3).Objects, 2)
+}
+
+func TestLogMatchEventState(t *testing.T) {
+	a := assert.New(t)
+	var msgs []GameStateMessage
+	a.Nil(json.Unmarshal([]byte(testGameStates), &msgs))
+	event := &ArenaMatchEvent{}
+	for _, m := range msgs {
+		event.GreToClientEvent.GreToClientMessages = append(event.GreToClientEvent.GreToClientMessages, GreToClientMessages{
+			Type:             GREGameStateMessage,
+			GameStateMessage: m,
+		})
+	}
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(event)
+	state := match.Games[0].State()
+	a.Equal(3, state.Current().ID)
+	a.Len(state.At(1).InZone("ZoneType_Hand", 1), 2)
+}
+
+func TestGameStateRebuild(t *testing.T) {
+	a := assert.New(t)
+	g := NewGameState()
+	g.Apply(&GameStateMessage{Type: GameStateFull, GameStateID: 1, TurnInfo: &TurnInfo{TurnNumber: 1},
+		Players: []PlayerState{{LifeTotal: 20, SystemSeatNumber: 1}}})
+	for id := 2; id <= 100; id++ {
+		msg := &GameStateMessage{
+			Type:            GameStateDiff,
+			GameStateID:     id,
+			PrevGameStateID: id - 1,
+			Players:         []PlayerState{{LifeTotal: id, SystemSeatNumber: 1}},
+			GameObjects:     []ArenaMatchGameObject{{InstanceID: id, GrpID: id}},
+		}
+		if id%40 == 0 {
+			msg.TurnInfo = &TurnInfo{TurnNumber: id/40 + 1}
+		}
+		g.Apply(msg)
+	}
+	keyframes := 0
+	for _, s := range g.applied {
+		if s.keyframe != nil {
+			keyframes++
+		}
+	}
+	a.True(keyframes < 10)
+	for id := 2; id <= 100; id++ {
+		s := g.At(id)
+		a.Equal(id, s.ID)
+		a.Equal(id, s.Life(1))
+		a.Len(s.Objects, id-1)
+	}
+	a.Equal(79, g.AtTurn(2).ID)
+	a.Equal(100, g.AtTurn(3).ID)
+	// Rebuilding a state doesn't change the one it was rebuilt from
+	g.At(55).Players[1] = PlayerState{LifeTotal: 1, SystemSeatNumber: 1}
+	a.Equal(55, g.At(55).Life(1))
+}
+
+func TestGameStateDiffsFromSameState(t *testing.T) {
+	a := assert.New(t)
+	g := NewGameState()
+	g.Apply(&GameStateMessage{Type: GameStateFull, GameStateID: 1,
+		Players: []PlayerState{{LifeTotal: 20, SystemSeatNumber: 1}}})
+	g.Apply(&GameStateMessage{Type: GameStateDiff, GameStateID: 2, PrevGameStateID: 1,
+		Players: []PlayerState{{LifeTotal: 18, SystemSeatNumber: 1}}})
+	g.Apply(&GameStateMessage{Type: GameStateDiff, GameStateID: 3, PrevGameStateID: 1,
+		Players: []PlayerState{{LifeTotal: 5, SystemSeatNumber: 1}}})
+	a.Equal(20, g.At(1).Life(1))
+	a.Equal(18, g.At(2).Life(1))
+	a.Equal(5, g.At(3).Life(1))
+}
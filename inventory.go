@@ -1,9 +1,5 @@
 package gathering
 
-import (
-	"encoding/json"
-)
-
 // ArenaPlayerInventory is your player profile details
 type ArenaPlayerInventory struct {
 	PlayerID        string  `json:"playerId"`
@@ -52,24 +48,24 @@ type ArenaInventoryUpdate struct {
 
 // IsPlayerInventory checks if a segment contains player inventory
 func (s *Segment) IsPlayerInventory() bool {
-	return s.SegmentType == PlayerInventoryGetPlayerInventory
+	return s.Is(PlayerInventoryGetPlayerInventory)
 }
 
 // IsInventoryUpdate checks if a segment is an inventory update
 func (s *Segment) IsInventoryUpdate() bool {
-	return s.SegmentType == IncomingInventoryUpdate
+	return s.Is(IncomingInventoryUpdate)
 }
 
 // ParsePlayerInventory parses the player inventory information from a segment
 func (s *Segment) ParsePlayerInventory() (*ArenaPlayerInventory, error) {
 	var inv ArenaPlayerInventory
-	err := json.Unmarshal(stripNonJSON(s.Text), &inv)
+	err := s.decodeObject(&inv)
 	return &inv, err
 }
 
 // ParseInventoryUpdate parses an incoming inventory update
 func (s *Segment) ParseInventoryUpdate() (*ArenaInventoryUpdate, error) {
 	var update ArenaInventoryUpdate
-	err := json.Unmarshal(stripNonJSON(s.Text), &update)
+	err := s.decodeObject(&update)
 	return &update, err
 }
@@ -0,0 +1,163 @@
+package gathering
+
+// CardMove is a card changing zones, from a ZoneTransfer annotation
+type CardMove struct {
+	Turn        int    `json:"turn"`
+	GameStateID int    `json:"gameStateId"`
+	InstanceID  int    `json:"instanceId"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Category    string `json:"category"`
+}
+
+// CardHistory is one physical card through a game. Arena gives a card a new
+// instance id every time it changes zones; InstanceIDs are all of them, in
+// order, and ID is the first.
+type CardHistory struct {
+	ID          int        `json:"id"`
+	InstanceIDs []int      `json:"instanceIds"`
+	GrpID       int        `json:"grpId"`
+	OwnerSeatID int        `json:"ownerSeatId"`
+	Moves       []CardMove `json:"moves"`
+}
+
+// turnOf is the turn of the first move matching, or 0 if it never happened
+func (c *CardHistory) turnOf(match func(m CardMove) bool) int {
+	for _, m := range c.Moves {
+		if match(m) {
+			return m.Turn
+		}
+	}
+	return 0
+}
+
+// DrawnTurn is the turn the card was drawn, 0 if it wasn't or was in the
+// opening hand
+func (c *CardHistory) DrawnTurn() int {
+	return c.turnOf(func(m CardMove) bool { return m.Category == "Draw" })
+}
+
+// CastTurn is the turn the card was first cast or played
+func (c *CardHistory) CastTurn() int {
+	return c.turnOf(func(m CardMove) bool {
+		return m.Category == "CastSpell" || m.Category == "PlayLand"
+	})
+}
+
+// DiedTurn is the turn the card first went from the battlefield to the
+// graveyard
+func (c *CardHistory) DiedTurn() int {
+	return c.turnOf(func(m CardMove) bool {
+		return m.From == "ZoneType_Battlefield" && m.To == "ZoneType_Graveyard"
+	})
+}
+
+// Lineage links the instance ids of each physical card in a game
+type Lineage struct {
+	cards      []*CardHistory
+	byInstance map[int]*CardHistory
+}
+
+// NewLineage creates an empty Lineage
+func NewLineage() *Lineage {
+	return &Lineage{
+		byInstance: make(map[int]*CardHistory),
+	}
+}
+
+// Card returns the card that has, or had, the instance id
+func (l *Lineage) Card(instanceID int) *CardHistory {
+	return l.byInstance[instanceID]
+}
+
+// Cards are all the cards seen, in the order they were first seen
+func (l *Lineage) Cards() []*CardHistory {
+	return l.cards
+}
+
+func (l *Lineage) card(instanceID int) *CardHistory {
+	if c, ok := l.byInstance[instanceID]; ok {
+		return c
+	}
+	c := &CardHistory{ID: instanceID, InstanceIDs: []int{instanceID}}
+	l.cards = append(l.cards, c)
+	l.byInstance[instanceID] = c
+	return c
+}
+
+// Link records that newID is the same card as origID
+func (l *Lineage) Link(origID, newID int) {
+	c := l.card(origID)
+	if other, ok := l.byInstance[newID]; ok && other != c {
+		// We saw the new id before the change, fold it into the older card
+		for _, id := range other.InstanceIDs {
+			l.byInstance[id] = c
+		}
+		c.InstanceIDs = append(c.InstanceIDs, other.InstanceIDs...)
+		c.Moves = append(c.Moves, other.Moves...)
+		if c.GrpID == 0 {
+			c.GrpID = other.GrpID
+		}
+		l.remove(other)
+		return
+	}
+	if _, ok := l.byInstance[newID]; !ok {
+		c.InstanceIDs = append(c.InstanceIDs, newID)
+		l.byInstance[newID] = c
+	}
+}
+
+func (l *Lineage) remove(c *CardHistory) {
+	for i, card := range l.cards {
+		if card == c {
+			l.cards = append(l.cards[:i], l.cards[i+1:]...)
+			return
+		}
+	}
+}
+
+// Observe records what an object is. Cards hidden from the player have no
+// grpId until they are revealed.
+func (l *Lineage) Observe(o ArenaMatchGameObject) {
+	if o.Type != "GameObjectType_Card" {
+		return
+	}
+	c := l.card(o.InstanceID)
+	if o.GrpID != 0 {
+		c.GrpID = o.GrpID
+	}
+	if o.OwnerSeatID != 0 {
+		c.OwnerSeatID = o.OwnerSeatID
+	}
+}
+
+// apply follows the cards through a game state and the events that came
+// with it
+func (l *Lineage) apply(msg *GameStateMessage, events []*GameEvent, turn int) {
+	for _, e := range events {
+		if e.ObjectIDChanged != nil {
+			l.Link(e.ObjectIDChanged.OrigID, e.ObjectIDChanged.NewID)
+		}
+	}
+	for _, o := range msg.GameObjects {
+		l.Observe(o)
+	}
+	for _, e := range events {
+		t := e.ZoneTransfer
+		if t == nil {
+			continue
+		}
+		c := l.card(t.InstanceID)
+		c.Moves = append(c.Moves, CardMove{
+			Turn:        turn,
+			GameStateID: e.GameStateID,
+			InstanceID:  t.InstanceID,
+			From:        t.ZoneSrcType,
+			To:          t.ZoneDestType,
+			Category:    t.Category,
+		})
+		if c.GrpID == 0 {
+			c.GrpID = t.GrpID
+		}
+	}
+}
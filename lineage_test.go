@@ -0,0 +1,80 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameLineage(t *testing.T) {
+	a := assert.New(t)
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(testAnnotationEvent), &event))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&event)
+	l := match.Games[0].Lineage()
+
+	spell := l.Card(161)
+	a.Equal(spell, l.Card(160))
+	a.Equal(160, spell.ID)
+	a.Equal([]int{160, 161}, spell.InstanceIDs)
+	a.Equal(68739, spell.GrpID)
+	a.Equal(4, spell.CastTurn())
+	a.Equal(0, spell.DiedTurn())
+
+	// 171 has no ObjectIdChanged, so it isn't linked to 170
+	died := l.Card(171)
+	a.Equal(4, died.DiedTurn())
+	a.Equal(68800, died.GrpID)
+	a.NotEqual(died, l.Card(170))
+	// Two copies of a card stay two cards
+	a.Len(l.Cards(), 3)
+}
+
+func TestLineageLink(t *testing.T) {
+	a := assert.New(t)
+	l := NewLineage()
+	l.Observe(ArenaMatchGameObject{InstanceID: 1, Type: "GameObjectType_Card", OwnerSeatID: 1})
+	l.Observe(ArenaMatchGameObject{InstanceID: 2, GrpID: 100, Type: "GameObjectType_Card", OwnerSeatID: 1})
+	l.Observe(ArenaMatchGameObject{InstanceID: 9, GrpID: 100, Type: "GameObjectType_Ability"})
+	a.Len(l.Cards(), 2)
+	l.Link(1, 2)
+	l.Link(2, 3)
+	a.Len(l.Cards(), 1)
+	c := l.Card(3)
+	a.Equal([]int{1, 2, 3}, c.InstanceIDs)
+	a.Equal(100, c.GrpID)
+	a.Nil(l.Card(9))
+
+	c.Moves = []CardMove{
+		{Turn: 0, From: "ZoneType_Library", To: "ZoneType_Hand", Category: "Draw"},
+		{Turn: 3, From: "ZoneType_Library", To: "ZoneType_Hand", Category: "Draw"},
+	}
+	a.Equal(0, c.DrawnTurn())
+	a.Equal(0, c.CastTurn())
+}
+
+func TestGameLineageSeenObjects(t *testing.T) {
+	a := assert.New(t)
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(testAnnotationEvent), &event))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&event)
+	g := match.Games[0]
+	// The spell is one card across its instance ids, the two copies of 68800
+	// are two cards
+	seen := g.SeenObjects[0]
+	a.Len(seen, 3)
+	a.Equal(161, seen[0].InstanceID)
+	a.Equal(170, seen[1].InstanceID)
+	a.Equal(171, seen[2].InstanceID)
+
+	a.Len(g.Cards, 3)
+	b, err := json.Marshal(g)
+	a.Nil(err)
+	var saved ArenaGame
+	a.Nil(json.Unmarshal(b, &saved))
+	a.Equal([]int{160, 161}, saved.Cards[0].InstanceIDs)
+	a.Equal(4, saved.Cards[0].CastTurn())
+}
@@ -1,311 +1,119 @@
 package gathering
 
 import (
-	"bufio"
-	"bytes"
 	"errors"
-	"io"
 	"log"
 	"os"
 	"regexp"
-	"time"
 )
 
 // ErrNotFound is the error returned when a log item is not found
 var ErrNotFound = errors.New("not found")
 var segmentStartRegex = regexp.MustCompile(`\[UnityCrossThreadLogger\].*|\[Client GRE\]`)
 var clientGRE = []byte("Client GRE")
-var newLineDelimiter = []byte("\n")
-var findDate = regexp.MustCompile(`(?m)\d+\/\d+\/\d{4}\s\d+:\d+:\d+\s[APM]{2}`)
-var dateLayout = "1/2/2006 15:04:05 PM"
 
-// Log is the well-structured format of the output_log.txt, parsed into Segments
+// Log is the well-structured format of the output_log.txt, parsed into Segments.
+// Errors holds problems found while parsing that didn't stop the parse, such as
+// lines that were too long to read.
 type Log struct {
 	Segments []*Segment
+	Errors   []error
 }
 
 // ParseLog returns a log file parsed into Segments
 func ParseLog(f *os.File) (*Log, error) {
-	reader := bufio.NewReader(f)
-	var segments []*Segment
-	previous := &Segment{
-		Range: []int{0},
+	p := NewLogParser(nil)
+	if _, err := p.Parse(f); err != nil {
+		log.Printf("unexpected error reading log file: %v\n", err.Error())
 	}
-	// This won't succeed with lines longer than 4096 bytes, but a brief
-	// look through the log files show that lines generally aren't longer than
-	// 1000 characters, and those that _are_ we can ignore.
-	// https://stackoverflow.com/questions/8757389/reading-file-line-by-line-in-go
-	i := 0
-	var buffer bytes.Buffer
-	for {
-		i++
-		b, err := reader.ReadBytes('\n')
-		if err == io.EOF {
-			break
-		}
-		if err == bufio.ErrBufferFull {
-			continue
-		}
-		if err != nil {
-			log.Printf("unexpected error reading log file: %v\n", err.Error())
-			break
-		}
-		if segmentStartRegex.Match(b) {
-			t := UnityLogger
-			if bytes.Contains(b, clientGRE) {
-				t = ClientGRE
-			}
-			// buffer.WriteTo(previous.Text)
-			previous.Text = make([]byte, buffer.Len())
-			copy(previous.Text, buffer.Bytes())
-			// previous.Text = buffer.Bytes()
-			previous.Range = append(previous.Range, i)
-			previous.SegmentType = parseType(previous.Text)
-			segments = append(segments, previous)
-			buffer.Reset()
-			previous = &Segment{
-				LoggerType: t,
-				Time:       parseDate(b),
-				Line:       b,
-				Range:      []int{i},
-			}
-		} else {
-			buffer.Write(b)
-		}
-	}
-	previous.Text = buffer.Bytes()
-	previous.Range = append(previous.Range, i)
-	return &Log{
-		Segments: segments,
-	}, nil
+	p.Flush()
+	return p.Log(), nil
 }
 
 // Collection finds a collection
 func (l *Log) Collection() (map[string]int, error) {
-	// TODO: Put these all in the same for loop
-	for i := len(l.Segments) - 1; i >= 0; i-- {
-		s := l.Segments[i]
-		if s.IsCollection() {
-			return s.ParseCollection()
-		}
-	}
-	return nil, ErrNotFound
+	c := &collectionExtractor{}
+	l.walk(c)
+	return c.result()
 }
 
 // Rank finds the rank information
 // The game doesn't ask for the entire rank info often, so we
 // go through the log and update the parsed rank with changes
 // so we return the most up to date version
-func (l *Log) Rank() (rank *ArenaRankInfo, err error) {
-	for _, s := range l.Segments {
-		if s.IsRankInfo() {
-			rank, err = s.ParseRankInfo()
-			if err != nil {
-				return
-			}
-		}
-		if s.IsRankUpdated() && rank != nil {
-			updated, err := s.ParseRankUpdated()
-			if err != nil {
-				continue
-			}
-			rank.Update(updated)
-		}
-	}
-	return
+func (l *Log) Rank() (*ArenaRankInfo, error) {
+	r := &rankExtractor{}
+	l.walk(r)
+	return r.result()
 }
 
 // Inventory finds the player inventory information
 func (l *Log) Inventory() (*ArenaPlayerInventory, error) {
-	// TODO: Put in same loop
-	for i := len(l.Segments) - 1; i >= 0; i-- {
-		s := l.Segments[i]
-		if s.IsPlayerInventory() {
-			return s.ParsePlayerInventory()
-		}
-	}
-	return nil, ErrNotFound
+	v := &inventoryExtractor{}
+	l.walk(v)
+	return v.result()
 }
 
 // Auth finds the player's ingame name
 func (l *Log) Auth() ([]byte, error) {
-	// TODO: Put in same loop
-	for _, s := range l.Segments {
-		if s.IsPlayerAuth() {
-			return s.ParseAuth()
-		}
-	}
-	return nil, ErrNotFound
+	a := &authExtractor{}
+	l.walk(a)
+	return a.result()
 }
 
 // Decks finds the player decks
 func (l *Log) Decks() ([]ArenaDeck, error) {
-	// TODO: Put in same loop
-	for i := len(l.Segments) - 1; i >= 0; i-- {
-		s := l.Segments[i]
-		if s.IsArenaDecks() {
-			return s.ParseArenaDecks()
-		}
-	}
-	return nil, ErrNotFound
+	d := &decksExtractor{}
+	l.walk(d)
+	return d.result()
 }
 
 // Boosters finds all the opened boosters
 func (l *Log) Boosters() ([]*Booster, error) {
-	// TODO: Put in same loop
-	boosters := make([]*Booster, 0)
-	for _, s := range l.Segments {
-		if s.IsCrackBooster() {
-			b, err := s.ParseCrackBooster()
-			if err == nil {
-				boosters = append(boosters, b)
-			}
-		}
-	}
-	return boosters, nil
-}
-
-func reverseDeckLookup(segments []*Segment, i int) (*ArenaDeck, error) {
-	for j := i; j >= 0; j-- {
-		s := segments[j]
-		if s.JoinedEvent() {
-			course, err := s.ParseJoinedEvent()
-			if err != nil {
-				log.Printf("error parsing get player course: %v\n", err.Error())
-				return nil, err
-			}
-			return course.CourseDeck, nil
-		}
-	}
-	return nil, ErrNotFound
+	b := &boostersExtractor{}
+	l.walk(b)
+	return b.result(), nil
 }
 
 // Matches finds the player matches
 // This is a little more involved, since we really need 3 pieces of information
 // and they are not together.
-// First, we look for the start of a match. Once we find that, we look backward
-// until we figure out what deck they are using. Finally, we look forward again
+// First, we look for the start of a match. When we find that, the deck is the
+// one the player last joined a queue with. Finally, we look forward again
 // to find the result of the match.
 // The result may not be known when we start parsing, so those values are all
 // optional. The server only needs the MatchID to tie together the data.
 func (l *Log) Matches() ([]*ArenaMatch, error) {
-	// New Algo
-	matches := make(map[string]*ArenaMatch)
-	var match *ArenaMatch
-	for i, s := range l.Segments {
-		if s.IsMatchStart() {
-			var err error
-			match, err = s.ParseMatchStart()
-			match.Games = append(match.Games, &ArenaGame{
-				GameStart: match.GameStart,
-			})
-			if err != nil {
-				log.Printf("error parsing match start: %v\n", err.Error())
-				continue
-			}
-			deck, err := reverseDeckLookup(l.Segments, i)
-			if err != nil {
-				log.Printf("error finding match deck: %v\n", err.Error())
-				continue
-			}
-			match.CourseDeck = deck
-			matches[match.MatchID] = match
-		}
-		// same as normal, but the logs go into the current game
-		if match != nil && s.IsMatchEvent() {
-			event, err := s.ParseMatchEvent()
-			if err != nil {
-				log.Printf("error getting match event: %v\n", err.Error())
-				continue
-			}
-			match.LogMatchEvent(event)
-		}
-		if match != nil && s.IsSideboardStop() {
-			match.currentGame++
-			match.Games = append(match.Games, &ArenaGame{
-				GameStart: s.Time,
-			})
-		}
-		if s.IsMatchEnd() {
-			end, err := s.ParseMatchEnd()
-			if err != nil {
-				log.Printf("error parsing match end: %v\n", err.Error())
-				break
-			}
-			m := end.Params.PayloadObject
-			match = matches[*m.MatchID]
-			if match == nil || *m.MatchID != match.MatchID {
-				// We are missing the first part of the match.
-				// Get what we can and let the server figure out the rest.
-				continue
-			}
-			match.UpdateGameEnd(end.Params.PayloadObject)
-			matches[*m.MatchID] = match
-		}
-		if match != nil && s.IsMatchCompleted() {
-			end, err := s.ParseMatchCompleted()
-			if err != nil {
-				log.Printf("error parsing match end: %v\n", err.Error())
-				break
-			}
-			match.UpdateMatchCompleted(end)
-			match = nil
-		}
+	m := newMatchExtractor()
+	l.walk(m)
+	for _, p := range m.problems {
+		log.Printf("error parsing match: %v\n", p.Error())
 	}
-	var found []*ArenaMatch
-	for _, value := range matches {
-		found = append(found, value)
-	}
-	return found, nil
+	return m.result(), nil
 }
 
 // Events finds Arena Events in the logs
 func (l *Log) Events() ([]*ArenaEvent, error) {
-	events := make([]*ArenaEvent, 0)
-	for i, s := range l.Segments {
-		if s.IsClaimPrize() {
-			event := &ArenaEvent{}
-			claim, err := s.ParseEventClaimPrize()
-			if err != nil {
-				continue
-			}
-			event.ClaimPrize = claim
-			// Go back a few segments to find the inventory change
-			for j := i; j > i-10; j-- {
-				back := l.Segments[j]
-				if back.IsInventoryUpdate() {
-					update, err := back.ParseInventoryUpdate()
-					if err != nil {
-						break
-					}
-					event.Prize = update
-					break
-				}
-			}
-			events = append(events, event)
-		}
-	}
-	return events, nil
+	e := newEventExtractor()
+	l.walk(e)
+	return e.result(), nil
 }
 
-// Find and Parse 1/8/2019 2:07:00 PM
-func parseDate(line []byte) *time.Time {
-	date := findDate.Find(line)
-	t, err := time.ParseInLocation(dateLayout, string(date), time.Local)
-	if err != nil {
-		return nil
-	}
-	t = t.UTC()
-	return &t
+// parseType classifies a segment's text, returning its primary type and all
+// the types it matched
+func parseType(b []byte) (SegmentType, []SegmentType) {
+	s := &Segment{Text: b}
+	s.Method = segmentMethod(s)
+	return segmentType(s)
 }
 
-func parseType(b []byte) SegmentType {
-	for s, r := range segmentTypeChecks {
-		if r.Match(b) {
-			return s
-		}
+// segmentType classifies a segment whose Method has been found
+func segmentType(s *Segment) (SegmentType, []SegmentType) {
+	types := classify(s)
+	if len(types) == 0 {
+		return Unknown, nil
 	}
-	return Unknown
+	return types[0], types
 }
 
 // String to pointer
@@ -180,6 +180,7 @@ func TestLogEvents(t *testing.T) {
 	e := eventResults[0]
 	a.NotNil(e.ClaimPrize)
 	a.NotNil(e.Prize)
+	a.Equal("Constructed_Event", *e.ClaimPrizeRequest.Params.EventName)
 	a.Equal("00f2bcfe-e5c3-45ba-9950-05d10d2687ad", e.ClaimPrize.ID)
 	a.Equal("Constructed_Event", e.ClaimPrize.InternalEventName)
 	a.Equal(7, e.ClaimPrize.ModuleInstanceData.WinLossGate.MaxWins)
@@ -1,7 +1,6 @@
 package gathering
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -10,38 +9,122 @@ import (
 // three, main contain up to 3.
 type ArenaMatch struct {
 	currentGame                    int
-	MatchID                        string       `json:"matchId"`
-	Games                          []*ArenaGame `json:"games"`
-	GameStart                      *time.Time   `json:"gameStart"`
-	EventID                        string       `json:"eventId"`
-	OpponentScreenName             string       `json:"opponentScreenName"`
-	OpponentIsWotc                 bool         `json:"opponentIsWotc"`
-	OpponentRankingClass           string       `json:"opponentRankingClass"`
-	OpponentRankingTier            int          `json:"opponentRankingTier"`
-	OpponentMythicPercentile       float64      `json:"opponentMythicPercentile"`
-	OpponentMythicLeaderboardPlace int          `json:"opponentMythicLeaderboardPlace"`
-	CourseDeck                     *ArenaDeck   `json:"CourseDeck"`
+	MatchID                        string        `json:"matchId"`
+	Games                          []*ArenaGame  `json:"games"`
+	GameStart                      *time.Time    `json:"gameStart"`
+	EventID                        string        `json:"eventId"`
+	OpponentScreenName             string        `json:"opponentScreenName"`
+	OpponentIsWotc                 bool          `json:"opponentIsWotc"`
+	OpponentRankingClass           string        `json:"opponentRankingClass"`
+	OpponentRankingTier            int           `json:"opponentRankingTier"`
+	OpponentMythicPercentile       float64       `json:"opponentMythicPercentile"`
+	OpponentMythicLeaderboardPlace int           `json:"opponentMythicLeaderboardPlace"`
+	CourseDeck                     *ArenaDeck    `json:"CourseDeck"`
+	Result                         Result        `json:"result"`
+	Wins                           int           `json:"wins"`
+	Losses                         int           `json:"losses"`
+	MatchCompletedReason           string        `json:"matchCompletedReason"`
+	OpponentDeck                   *OpponentDeck `json:"opponentDeck"`
 }
 
 // ArenaGame is a game within a match
 type ArenaGame struct {
-	GameStart     *time.Time                     `json:"gameStart"`
-	Number        *int                           `json:"number"`
-	MatchID       *string                        `json:"matchId"`
-	SeatID        *int                           `json:"seatId"`
-	TeamID        *int                           `json:"teamId"`
-	WinningTeamID *int                           `json:"winningTeamId"`
-	WinningReason *string                        `json:"winningReason"`
-	TurnCount     *int                           `json:"turnCount"`
-	SecondsCount  *int                           `json:"secondsCount"`
-	CourseDeck    *ArenaDeck                     `json:"CourseDeck"`
-	SeenObjects   map[int][]ArenaMatchGameObject `json:"seenObjects"`
-}
-
-// UpdateGameEnd updates the latest game with the game result
+	GameStart        *time.Time                     `json:"gameStart"`
+	Number           *int                           `json:"number"`
+	MatchID          *string                        `json:"matchId"`
+	SeatID           *int                           `json:"seatId"`
+	TeamID           *int                           `json:"teamId"`
+	WinningTeamID    *int                           `json:"winningTeamId"`
+	WinningReason    *string                        `json:"winningReason"`
+	TurnCount        *int                           `json:"turnCount"`
+	SecondsCount     *int                           `json:"secondsCount"`
+	CourseDeck       *ArenaDeck                     `json:"CourseDeck"`
+	SeenObjects      map[int][]ArenaMatchGameObject `json:"seenObjects"`
+	Turns            []*ArenaTurn                   `json:"turns"`
+	Mulligans        *ArenaMulligans                `json:"mulligans"`
+	StartingTeamID   *int                           `json:"startingTeamId"`
+	StartingSeatID   *int                           `json:"startingSeatId"`
+	OnThePlay        *bool                          `json:"onThePlay"`
+	DieRolls         map[int]int                    `json:"dieRolls"`
+	Result           Result                         `json:"result"`
+	SideboardDiff    *ArenaDeckDiff                 `json:"sideboardDiff"`
+	Actions          []*ArenaAction                 `json:"actions"`
+	Clock            map[int]*ArenaClock            `json:"clock"`
+	RopeShownCount   *int                           `json:"ropeShownCount"`
+	RopeExpiredCount *int                           `json:"ropeExpiredCount"`
+	LostToTimeout    bool                           `json:"lostToTimeout"`
+	Cards            []*CardHistory                 `json:"cards"`
+	seat             int
+	state            *GameState
+	lineage          *Lineage
+	timers           map[int]Timer
+	shown            *shownCards
+}
+
+// State is the game as rebuilt from its GameStateMessages
+func (g *ArenaGame) State() *GameState {
+	if g.state == nil {
+		g.state = NewGameState()
+	}
+	return g.state
+}
+
+// Lineage follows every card in the game across its instance ids
+func (g *ArenaGame) Lineage() *Lineage {
+	if g.lineage == nil {
+		g.lineage = NewLineage()
+	}
+	return g.lineage
+}
+
+// game returns the game with the number, adding games up to it if needed
+func (a *ArenaMatch) game(number int) *ArenaGame {
+	for len(a.Games) < number {
+		a.Games = append(a.Games, &ArenaGame{})
+	}
+	return a.Games[number-1]
+}
+
+// startGame makes the game with the number the current one. Unless a new
+// deck was submitted for it, it is played with the deck of the game before.
+func (a *ArenaMatch) startGame(number int, start *time.Time) *ArenaGame {
+	game := a.game(number)
+	a.currentGame = number - 1
+	if game.GameStart == nil {
+		game.GameStart = start
+	}
+	if game.CourseDeck == nil && number > 1 && a.Games[number-2].CourseDeck != nil {
+		a.setDeck(game, a.Games[number-2].CourseDeck)
+	}
+	return game
+}
+
+// nextGameNumber is the number of the game after the last one that ended
+func (a *ArenaMatch) nextGameNumber() int {
+	number := 1
+	for i, g := range a.Games {
+		if g.Number != nil {
+			number = i + 2
+		}
+	}
+	return number
+}
+
+// NextGame moves on to the game after the last one that ended, like when
+// sideboarding is done. The GRE messages of the game move to it too, so this
+// is only needed to get the start time from the log.
+func (a *ArenaMatch) NextGame(start *time.Time) *ArenaGame {
+	return a.startGame(a.nextGameNumber(), start)
+}
+
+// UpdateGameEnd updates the game with the game result. The game number from
+// DuelScene.GameStop is used when it has one, otherwise the current game.
 func (a *ArenaMatch) UpdateGameEnd(end *ArenaGame) {
-	game := a.Games[a.currentGame]
 	num := a.currentGame + 1
+	if end.Number != nil && *end.Number > 0 {
+		num = *end.Number
+	}
+	game := a.startGame(num, nil)
 	game.MatchID = &a.MatchID
 	game.SeatID = end.SeatID
 	game.TeamID = end.TeamID
@@ -50,36 +133,129 @@ func (a *ArenaMatch) UpdateGameEnd(end *ArenaGame) {
 	game.WinningReason = end.WinningReason
 	game.TurnCount = end.TurnCount
 	game.SecondsCount = end.SecondsCount
+	game.RopeShownCount = end.RopeShownCount
+	game.RopeExpiredCount = end.RopeExpiredCount
+	game.StartingTeamID = end.StartingTeamID
+	game.updateOnThePlay()
+	game.updateResult(a.teamID())
+	a.updateScore()
 }
 
 // UpdateMatchCompleted updates the match object with the completed
 // status
 func (a *ArenaMatch) UpdateMatchCompleted(com *ArenaMatchCompleted) {
-	// TODO: I'm not sure we need anything here, but maybe some sanity setters?
+	a.updateResults(&com.MatchGameRoomStateChangedEvent.GameRoomInfo.FinalMatchResult)
 }
 
-// LogMatchEvent adds an event to the log
+// updateGameInfo follows the game the GRE is talking about. When a game is
+// over its winner is taken from the results, for games that end without a
+// DuelScene.GameStop. The result is set once the state is applied, which has
+// the player's team.
+func (a *ArenaMatch) updateGameInfo(info *GameInfo, at *time.Time) *ArenaGame {
+	if info.GameNumber > 0 {
+		a.startGame(info.GameNumber, at)
+	}
+	game := a.Games[a.currentGame]
+	if info.MatchState != "MatchState_GameComplete" && info.MatchState != "MatchState_MatchComplete" {
+		return game
+	}
+	for i := len(info.Results) - 1; i >= 0 && game.WinningTeamID == nil; i-- {
+		r := info.Results[i]
+		if r.Scope != matchScopeGame {
+			continue
+		}
+		winner := r.WinningTeamID
+		game.WinningTeamID = &winner
+		if r.Reason != "" {
+			reason := r.Reason
+			game.WinningReason = &reason
+		}
+	}
+	return game
+}
+
+// LogMatchEvent adds an event to the log. Events go into the game in the last
+// GameInfo.GameNumber the GRE sent.
 func (a *ArenaMatch) LogMatchEvent(event *ArenaMatchEvent) {
-	game := a.Games[len(a.Games)-1]
-	if game.SeenObjects == nil {
-		game.SeenObjects = make(map[int][]ArenaMatchGameObject)
+	at := parseEpoch([]byte(event.Timestamp))
+	if len(a.Games) == 0 {
+		a.startGame(1, at)
 	}
+	games := make(map[*ArenaGame]bool)
 	for _, m := range event.GreToClientEvent.GreToClientMessages {
 		gsm := m.GameStateMessage
+		game := a.Games[a.currentGame]
+		switch m.Type {
+		case GREGameStateMessage, GREQueuedGameStateMessage:
+			if gsm.GameInfo != nil {
+				game = a.updateGameInfo(gsm.GameInfo, at)
+			}
+			prev := game.State().Current()
+			next := game.State().Apply(&gsm)
+			game.recordTurn(prev, next, at)
+			events := decodeAnnotations(gsm.Annotations, prev, next)
+			game.recordEvents(events, next)
+			game.Lineage().apply(&gsm, events, next.Turn())
+			game.shownCards().add(&gsm)
+			game.recordMulliganState(next)
+			game.recordStartingSeat(next)
+			if game.Result == "" && game.WinningTeamID != nil {
+				game.updateResult(a.teamID())
+				a.updateScore()
+			}
+		case GREMulliganReq, GREGroupReq:
+			game.recordMulliganReq(&m)
+		case GRETimerStateMessage:
+			if m.TimerStateMessage != nil {
+				game.recordTimers(m.TimerStateMessage)
+			}
+		case GRETimeoutMessage:
+			game.recordTimeout(&m)
+		case GREConnectResp:
+			if m.ConnectResp != nil && m.ConnectResp.DeckMessage != nil {
+				a.setDeck(game, m.ConnectResp.DeckMessage.ArenaDeck())
+			}
+		}
+		game.recordStart(&m)
+		if game.SeenObjects == nil {
+			game.SeenObjects = make(map[int][]ArenaMatchGameObject)
+		}
 		for _, o := range gsm.GameObjects {
 			game.SeenObjects[o.OwnerSeatID] = append(game.SeenObjects[o.OwnerSeatID], o)
 		}
+		games[game] = true
+	}
+	for game := range games {
+		game.uniqueSeenObjects()
+		game.Cards = game.Lineage().Cards()
 	}
-	for k, v := range game.SeenObjects {
-		uniq := make(map[string]bool)
+}
+
+// uniqueSeenObjects removes repeated cards and non-card objects from
+// SeenObjects. A card is the same card across instance ids, and keeps the
+// last time it was seen with a grpId.
+func (g *ArenaGame) uniqueSeenObjects() {
+	for k, v := range g.SeenObjects {
+		uniq := make(map[int]int)
 		var objects []ArenaMatchGameObject
 		for _, o := range v {
-			if _, ok := uniq[o.Hash()]; !ok && o.Type == "GameObjectType_Card" {
-				uniq[o.Hash()] = true
-				objects = append(objects, o)
+			if o.Type != "GameObjectType_Card" {
+				continue
+			}
+			id := o.InstanceID
+			if c := g.Lineage().Card(o.InstanceID); c != nil {
+				id = c.ID
 			}
+			if i, ok := uniq[id]; ok {
+				if o.GrpID != 0 {
+					objects[i] = o
+				}
+				continue
+			}
+			uniq[id] = len(objects)
+			objects = append(objects, o)
 		}
-		game.SeenObjects[k] = objects
+		g.SeenObjects[k] = objects
 	}
 }
 
@@ -97,17 +273,36 @@ type GreToClientEvent struct {
 
 // GreToClientMessages see log
 type GreToClientMessages struct {
-	Type             string           `json:"type"`
-	GameStateMessage GameStateMessage `json:"gameStateMessage"`
+	Type               string              `json:"type"`
+	SystemSeatIDs      []int               `json:"systemSeatIds"`
+	GameStateID        int                 `json:"gameStateId"`
+	GameStateMessage   GameStateMessage    `json:"gameStateMessage"`
+	MulliganReq        *MulliganReq        `json:"mulliganReq"`
+	GroupReq           *GroupReq           `json:"groupReq"`
+	DieRollResultsResp *DieRollResultsResp `json:"dieRollResultsResp"`
+	ConnectResp        *ConnectResp        `json:"connectResp"`
+	TimerStateMessage  *TimerStateMessage  `json:"timerStateMessage"`
+	TimeoutMessage     *TimeoutMessage     `json:"timeoutMessage"`
+}
+
+// ConnectResp is sent when the client connects to a game, with the deck it
+// will play with
+type ConnectResp struct {
+	DeckMessage *DeckMessage `json:"deckMessage"`
 }
 
 // GameStateMessage see log
 type GameStateMessage struct {
-	Type        string                 `json:"type"`
-	GameObjects []ArenaMatchGameObject `json:"gameObjects"`
-	TurnInfo    *TurnInfo              `json:"turnInfo"`
-	Players     []PlayerState          `json:"players"`
-	GameInfo    *GameInfo              `json:"gameInfo"`
+	Type                   string                 `json:"type"`
+	GameStateID            int                    `json:"gameStateId"`
+	PrevGameStateID        int                    `json:"prevGameStateId"`
+	GameObjects            []ArenaMatchGameObject `json:"gameObjects"`
+	Zones                  []Zone                 `json:"zones"`
+	TurnInfo               *TurnInfo              `json:"turnInfo"`
+	Players                []PlayerState          `json:"players"`
+	GameInfo               *GameInfo              `json:"gameInfo"`
+	DiffDeletedInstanceIDs []int                  `json:"diffDeletedInstanceIds"`
+	Annotations            []Annotation           `json:"annotations"`
 }
 
 // GameInfo contains match info, such as which game this is
@@ -128,7 +323,7 @@ type GameInfo struct {
 
 // MatchResult has a list of who won the games in a match
 type MatchResult struct {
-	Scope         string `json:" scope"`
+	Scope         string `json:"scope"`
 	Result        string `json:"result"`
 	WinningTeamID int    `json:"winningTeamId"`
 	Reason        string `json:"reason"`
@@ -140,24 +335,39 @@ type PlayerState struct {
 	SystemSeatNumber int `json:"systemSeatNumber"`
 	TeamID           int `json:"teamId"`
 	ControllerSeatID int `json:"controllerSeatId"`
+	MulliganCount    int `json:"mulliganCount"`
 }
 
 // TurnInfo see log
 type TurnInfo struct {
-	Phase        string `json:"phase"`
-	Step         string `json:"step"`
-	TurnNumber   int    `json:"turnNumber"`
-	ActivePlayer int    `json:"activePlayer"`
+	Phase          string `json:"phase"`
+	Step           string `json:"step"`
+	TurnNumber     int    `json:"turnNumber"`
+	ActivePlayer   int    `json:"activePlayer"`
+	PriorityPlayer int    `json:"priorityPlayer"`
+	DecisionPlayer int    `json:"decisionPlayer"`
 }
 
 // ArenaMatchGameObject is a game object in a match
 type ArenaMatchGameObject struct {
-	InstanceID  int    `json:"instanceId"`
-	GrpID       int    `json:"grpId"`
-	Type        string `json:"type"`
-	ZoneID      int    `json:"zoneId"`
-	Visibility  string `json:"visibility"`
-	OwnerSeatID int    `json:"ownerSeatId"`
+	InstanceID       int         `json:"instanceId"`
+	GrpID            int         `json:"grpId"`
+	Type             string      `json:"type"`
+	ZoneID           int         `json:"zoneId"`
+	Visibility       string      `json:"visibility"`
+	OwnerSeatID      int         `json:"ownerSeatId"`
+	ControllerSeatID int         `json:"controllerSeatId"`
+	CardTypes        []string    `json:"cardTypes"`
+	Subtypes         []string    `json:"subtypes"`
+	Power            *ArenaValue `json:"power"`
+	Toughness        *ArenaValue `json:"toughness"`
+	IsTapped         bool        `json:"isTapped"`
+	Color            []string    `json:"color"`
+}
+
+// ArenaValue is how the GRE sends numbers that can be modified, like power
+type ArenaValue struct {
+	Value int `json:"value"`
 }
 
 // Start ArenaMatchCompleted
@@ -199,13 +409,15 @@ type MatchCompletedResult struct {
 	Scope         string `json:"scope"`
 	Result        string `json:"result"`
 	WinningTeamID int    `json:"winningTeamId"`
+	Reason        string `json:"reason"`
 }
 
 /*********************** End ArenaMatchCompleted ***************************/
 
-// Hash returns a unique string for this object
+// Hash returns a unique string for this object. Copies of a card have the
+// same grpId, so this is the instance id.
 func (a ArenaMatchGameObject) Hash() string {
-	return fmt.Sprintf("%d", a.GrpID)
+	return fmt.Sprintf("%d", a.InstanceID)
 }
 
 // ArenaMatchEndParams are the params which hold the results of the match
@@ -220,25 +432,25 @@ type ArenaMatchEnd struct {
 
 // IsMatchStart does this segment contain match start
 func (s *Segment) IsMatchStart() bool {
-	return s.SegmentType == MatchStart
+	return s.Is(MatchStart)
 }
 
 // IsMatchEnd does this segment contain a match end
 func (s *Segment) IsMatchEnd() bool {
-	return s.SegmentType == MatchEnd
+	return s.Is(MatchEnd)
 }
 
 // IsMatchCompleted is a better metric for a match (including ALL games) being
 // completed.
 func (s *Segment) IsMatchCompleted() bool {
-	return s.SegmentType == MatchCompleted
+	return s.Is(MatchCompleted)
 }
 
 // ParseMatchStart parses out the match start (will return an incomplete
 // ArenaMatch object)
 func (s *Segment) ParseMatchStart() (*ArenaMatch, error) {
 	var match ArenaMatch
-	err := json.Unmarshal(stripNonJSON(s.Text), &match)
+	err := s.decodeObject(&match)
 	if s.Time != nil {
 		match.GameStart = s.Time
 	}
@@ -248,7 +460,22 @@ func (s *Segment) ParseMatchStart() (*ArenaMatch, error) {
 // ParseMatchEnd parses the match end. Contains the match ID
 func (s *Segment) ParseMatchEnd() (*ArenaMatchEnd, error) {
 	var match ArenaMatchEnd
-	err := json.Unmarshal(stripNonJSON(s.Text), &match)
+	err := s.decodeObject(&match)
+	if err != nil || match.Params == nil || match.Params.PayloadObject == nil {
+		return &match, err
+	}
+	// The game number is gameNumber here, and number in ArenaGame
+	var stop struct {
+		Params struct {
+			PayloadObject struct {
+				GameNumber int `json:"gameNumber"`
+			} `json:"payloadObject"`
+		} `json:"params"`
+	}
+	if s.decodeObject(&stop) == nil && stop.Params.PayloadObject.GameNumber > 0 {
+		number := stop.Params.PayloadObject.GameNumber
+		match.Params.PayloadObject.Number = &number
+	}
 	return &match, err
 }
 
@@ -256,25 +483,25 @@ func (s *Segment) ParseMatchEnd() (*ArenaMatchEnd, error) {
 // are finished.
 func (s *Segment) ParseMatchCompleted() (*ArenaMatchCompleted, error) {
 	var done ArenaMatchCompleted
-	err := json.Unmarshal(stripNonJSON(s.Text), &done)
+	err := s.decodeObject(&done)
 	return &done, err
 }
 
 // IsMatchEvent checks if this segment contains anything interesting
 // about a currently parsing match
 func (s *Segment) IsMatchEvent() bool {
-	return s.SegmentType == MatchEvent
+	return s.Is(MatchEvent)
 }
 
 // IsSideboardStop checks if this is a sideboard end event
 func (s *Segment) IsSideboardStop() bool {
-	return s.SegmentType == DuelSceneSideboardingStop
+	return s.Is(DuelSceneSideboardingStop)
 }
 
 // ParseMatchEvent looks through the match segments and pulls out
 // cards played by whom
 func (s *Segment) ParseMatchEvent() (*ArenaMatchEvent, error) {
 	var event ArenaMatchEvent
-	err := json.Unmarshal(stripNonJSON(s.Text), &event)
+	err := s.decodeObject(&event)
 	return &event, err
 }
@@ -0,0 +1,87 @@
+package gathering
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// methodSearchSize is how far into a segment we look for the method line. It
+// is always at the start, and payloads can be megabytes long.
+const methodSearchSize = 512
+
+// ==> Event.GetPlayerCourseV2(296):
+// <== Event.GetPlayerCourseV2(296)
+var methodRegex = regexp.MustCompile(`(?m)^\s*(==>|<==)\s([A-Za-z]+\.[A-Za-z]+?)(?:V(\d+))?\((\d*)\)`)
+
+// ArenaMethod is the API method a segment is a request (==>) or response (<==)
+// for. The version suffix is split from the name, so
+// `Event.GetPlayerCourseV2` has the Name `Event.GetPlayerCourse` and Version 2.
+// Version is 0 for methods without a suffix. Decoders can check the Version
+// when a payload changed shape between versions. ID ties a request to its
+// response.
+type ArenaMethod struct {
+	Name     string `json:"name"`
+	Version  int    `json:"version"`
+	ID       int    `json:"id"`
+	Response bool   `json:"response"`
+}
+
+// parseMethod finds the method at the start of b
+func parseMethod(b []byte) *ArenaMethod {
+	if len(b) > methodSearchSize {
+		b = b[:methodSearchSize]
+	}
+	m := methodRegex.FindSubmatch(b)
+	if m == nil {
+		return nil
+	}
+	version, _ := strconv.Atoi(string(m[3]))
+	id, _ := strconv.Atoi(string(m[4]))
+	return &ArenaMethod{
+		Name:     string(m[2]),
+		Version:  version,
+		ID:       id,
+		Response: string(m[1]) == "<==",
+	}
+}
+
+// segmentMethod finds the method of a segment. Some versions of the log put it
+// in the header line, others on the line after.
+func segmentMethod(s *Segment) *ArenaMethod {
+	if m := parseMethod(headerRegex.ReplaceAll(s.Line, nil)); m != nil {
+		return m
+	}
+	return parseMethod(s.Text)
+}
+
+var headerRegex = regexp.MustCompile(`^\[[^\]]*\][^=<]*`)
+
+type methodMatcher struct {
+	name     string
+	response bool
+}
+
+func (m methodMatcher) Match(b []byte) bool {
+	return m.matchMethod(parseMethod(b))
+}
+
+// matchSegment uses the method already found for the segment, which may have
+// come from the header line rather than the text
+func (m methodMatcher) matchSegment(s *Segment) bool {
+	return m.matchMethod(s.Method)
+}
+
+func (m methodMatcher) matchMethod(method *ArenaMethod) bool {
+	return method != nil && method.Name == m.name && method.Response == m.response
+}
+
+// MethodResponse matches the response to an API method, whatever its version.
+// name is the method without a version, like `Deck.GetDeckLists`.
+func MethodResponse(name string) Matcher {
+	return methodMatcher{name: name, response: true}
+}
+
+// MethodRequest matches a request for an API method, whatever its version
+func MethodRequest(name string) Matcher {
+	return methodMatcher{name: name}
+}
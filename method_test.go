@@ -0,0 +1,84 @@
+package gathering
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMethod(t *testing.T) {
+	a := assert.New(t)
+	methods := map[string]*ArenaMethod{
+		"<== PlayerInventory.GetPlayerCardsV3(300)\n{}":     {Name: "PlayerInventory.GetPlayerCards", Version: 3, ID: 300, Response: true},
+		"\n<== Event.GetPlayerCourse(63)\n{}":               {Name: "Event.GetPlayerCourse", Version: 0, ID: 63, Response: true},
+		"==> Event.GetPlayerCourseV2(296):\n{}":             {Name: "Event.GetPlayerCourse", Version: 2, ID: 296, Response: false},
+		"<== Event.GetPlayerCoursesV2(311)\n[]":             {Name: "Event.GetPlayerCourses", Version: 2, ID: 311, Response: true},
+		"<== PlayerInventory.GetPlayerInventory()\n{}":      {Name: "PlayerInventory.GetPlayerInventory", Version: 0, Response: true},
+		"(-1) Incoming Inventory.Updated {}":                nil,
+		"{\"message\": \"<== Event.ClaimPrize(1)\"}\n<== x": nil,
+	}
+	for text, expected := range methods {
+		a.Equal(expected, parseMethod([]byte(text)), text)
+	}
+}
+
+func TestSegmentMethodInHeader(t *testing.T) {
+	a := assert.New(t)
+	s := &Segment{
+		Line: []byte("[UnityCrossThreadLogger]4/2/2019 3:01:48 PM: <== Deck.GetDeckListsV3(302)\n"),
+		Text: []byte("[]"),
+	}
+	a.Equal(&ArenaMethod{Name: "Deck.GetDeckLists", Version: 3, ID: 302, Response: true}, segmentMethod(s))
+}
+
+func TestClassifyMethodInHeader(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	_, err := p.Parse(strings.NewReader(`[UnityCrossThreadLogger]4/2/2019 3:01:48 PM: <== Deck.GetDeckListsV3(302)
+[{"id": "deck1", "name": "RDW", "mainDeck": [{"id": 1, "quantity": 4}]}]
+`))
+	a.Nil(err)
+	s := p.Flush()
+	a.Equal(DeckGetDeckLists, s.SegmentType)
+	decks, err := p.Log().Decks()
+	a.Nil(err)
+	a.Len(decks, 1)
+	a.Equal("RDW", decks[0].Name)
+}
+
+func TestClassifyAnyVersion(t *testing.T) {
+	a := assert.New(t)
+	types := map[string]SegmentType{
+		"<== Event.GetPlayerCourseV2(296)\n{}":  EventGetPlayerCourse,
+		"<== Event.GetPlayerCourse(63)\n{}":     EventGetPlayerCourse,
+		"<== Event.GetPlayerCoursesV2(311)\n[]": Unknown,
+		"==> Event.GetPlayerCourseV2(296):\n{}": Unknown,
+		"<== Deck.GetDeckListsV4(9)\n[]":        DeckGetDeckLists,
+		"<== Event.DeckSubmitV3(12)\n{}":        EventDeckSubmit,
+	}
+	for text, expected := range types {
+		primary, _ := parseType([]byte(text))
+		a.Equal(expected, primary, text)
+	}
+}
+
+func TestMatchDeckLookupV2(t *testing.T) {
+	a := assert.New(t)
+	f, err := os.Open("test/march-constructed.txt")
+	a.Nil(err)
+	alog, err := ParseLog(f)
+	a.Nil(err)
+	m := newMatchExtractor()
+	alog.walk(m)
+	deck, err := m.deck()
+	a.Nil(err)
+	a.Equal("RDW", deck.Name)
+	a.Equal("acd08352-afba-467f-b3f0-9907fec24513", deck.ID)
+	for _, s := range alog.Segments {
+		if s.IsEventGetPlayerCourse() {
+			a.Equal(2, s.Method.Version)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package gathering
+
+// The GRE messages about mulligans
+const (
+	GREMulliganReq = "GREMessageType_MulliganReq"
+	GREGroupReq    = "GREMessageType_GroupReq"
+)
+
+// londonMulligan is the GroupReq context for choosing the cards to put on the
+// bottom of the library after a London mulligan
+const londonMulligan = "GroupingContext_LondonMulligan"
+
+// MulliganReq asks the player to keep or mulligan their hand
+type MulliganReq struct {
+	MulliganType  string `json:"mulliganType"`
+	MulliganCount int    `json:"mulliganCount"`
+}
+
+// GroupReq asks the player to split cards into groups, like putting cards on
+// the bottom after a London mulligan
+type GroupReq struct {
+	InstanceIDs []int  `json:"instanceIds"`
+	Context     string `json:"context"`
+}
+
+// ArenaMulligans are the mulligan decisions in a game. Hands are the player's
+// opening hands by grpId, in the order they were offered, so the last one is
+// the one that was kept. Counts is how many times each seat mulliganed.
+// Bottomed are the cards put on the bottom of the library from the kept hand.
+type ArenaMulligans struct {
+	Type     string      `json:"type"`
+	Hands    [][]int     `json:"hands"`
+	Counts   map[int]int `json:"counts"`
+	Bottomed []int       `json:"bottomed"`
+
+	bottoming []ArenaMatchGameObject
+	seat      int
+}
+
+// Kept is the hand the player kept, without the cards they bottomed
+func (m *ArenaMulligans) Kept() []int {
+	if len(m.Hands) == 0 {
+		return nil
+	}
+	hand := m.Hands[len(m.Hands)-1]
+	bottomed := make(map[int]int)
+	for _, id := range m.Bottomed {
+		bottomed[id]++
+	}
+	var kept []int
+	for _, id := range hand {
+		if bottomed[id] > 0 {
+			bottomed[id]--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	return kept
+}
+
+func (g *ArenaGame) mulligans() *ArenaMulligans {
+	if g.Mulligans == nil {
+		g.Mulligans = &ArenaMulligans{
+			Counts: make(map[int]int),
+		}
+	}
+	return g.Mulligans
+}
+
+// handOf returns the cards in a seat's hand, by instanceId
+func handOf(s *GameSnapshot, seat int) map[int]int {
+	hand := make(map[int]int)
+	for _, o := range s.InZone("ZoneType_Hand", seat) {
+		hand[o.InstanceID] = o.GrpID
+	}
+	return hand
+}
+
+// recordMulliganState picks up the mulligan counts and type from a state, and
+// the cards bottomed once they leave the hand
+func (g *ArenaGame) recordMulliganState(s *GameSnapshot) {
+	if s.GameInfo != nil && s.GameInfo.MulliganType != "" {
+		g.mulligans().Type = s.GameInfo.MulliganType
+	}
+	for seat, p := range s.Players {
+		if p.MulliganCount > 0 {
+			g.mulligans().Counts[seat] = p.MulliganCount
+		}
+	}
+	m := g.Mulligans
+	if m == nil || m.bottoming == nil {
+		return
+	}
+	hand := handOf(s, m.seat)
+	var bottomed []int
+	for _, o := range m.bottoming {
+		if _, ok := hand[o.InstanceID]; !ok {
+			bottomed = append(bottomed, o.GrpID)
+		}
+	}
+	if len(bottomed) > 0 {
+		m.Bottomed = bottomed
+		m.bottoming = nil
+	}
+}
+
+// recordMulliganReq records the hand the player was asked to keep, or the
+// hand they are choosing cards to bottom from
+func (g *ArenaGame) recordMulliganReq(msg *GreToClientMessages) {
+	if len(msg.SystemSeatIDs) == 0 {
+		return
+	}
+	seat := msg.SystemSeatIDs[0]
+	s := g.State().At(msg.GameStateID)
+	if s == nil {
+		s = g.State().Current()
+	}
+	m := g.mulligans()
+	m.seat = seat
+	switch {
+	case msg.MulliganReq != nil:
+		if msg.MulliganReq.MulliganType != "" {
+			m.Type = msg.MulliganReq.MulliganType
+		}
+		var hand []int
+		for _, o := range s.InZone("ZoneType_Hand", seat) {
+			hand = append(hand, o.GrpID)
+		}
+		m.Hands = append(m.Hands, hand)
+	case msg.GroupReq != nil && msg.GroupReq.Context == londonMulligan:
+		m.bottoming = s.InZone("ZoneType_Hand", seat)
+	}
+}
@@ -0,0 +1,98 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMulliganEvent = `{
+ "greToClientEvent": {"greToClientMessages": [
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Full", "gameStateId": 1,
+   "gameInfo": {"mulliganType": "MulliganType_London"},
+   "players": [{"systemSeatNumber": 1}, {"systemSeatNumber": 2}],
+   "zones": [{"zoneId": 31, "type": "ZoneType_Hand", "ownerSeatId": 1, "objectInstanceIds": [160, 161, 162]}],
+   "gameObjects": [
+    {"instanceId": 160, "grpId": 1, "zoneId": 31, "ownerSeatId": 1},
+    {"instanceId": 161, "grpId": 2, "zoneId": 31, "ownerSeatId": 1},
+    {"instanceId": 162, "grpId": 3, "zoneId": 31, "ownerSeatId": 1}
+   ]}},
+  {"type": "GREMessageType_MulliganReq", "systemSeatIds": [1], "gameStateId": 1,
+   "mulliganReq": {"mulliganType": "MulliganType_London"}},
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Diff", "gameStateId": 2, "prevGameStateId": 1,
+   "players": [{"systemSeatNumber": 1, "mulliganCount": 1}],
+   "zones": [{"zoneId": 31, "type": "ZoneType_Hand", "ownerSeatId": 1, "objectInstanceIds": [170, 171, 172]}],
+   "gameObjects": [
+    {"instanceId": 170, "grpId": 4, "zoneId": 31, "ownerSeatId": 1},
+    {"instanceId": 171, "grpId": 5, "zoneId": 31, "ownerSeatId": 1},
+    {"instanceId": 172, "grpId": 4, "zoneId": 31, "ownerSeatId": 1}
+   ],
+   "diffDeletedInstanceIds": [160, 161, 162]}},
+  {"type": "GREMessageType_MulliganReq", "systemSeatIds": [1], "gameStateId": 2,
+   "mulliganReq": {"mulliganType": "MulliganType_London", "mulliganCount": 1}},
+  {"type": "GREMessageType_GroupReq", "systemSeatIds": [1], "gameStateId": 2,
+   "groupReq": {"instanceIds": [170, 171, 172], "context": "GroupingContext_LondonMulligan"}},
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Diff", "gameStateId": 3, "prevGameStateId": 2,
+   "players": [{"systemSeatNumber": 2, "mulliganCount": 2}],
+   "zones": [{"zoneId": 31, "type": "ZoneType_Hand", "ownerSeatId": 1, "objectInstanceIds": [170, 172]}],
+   "diffDeletedInstanceIds": [171]}}
+ ]}
+}`
+
+func TestGameMulligans(t *testing.T) {
+	a := assert.New(t)
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(testMulliganEvent), &event))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&event)
+	m := match.Games[0].Mulligans
+	a.NotNil(m)
+	a.Equal("MulliganType_London", m.Type)
+	a.Equal([][]int{{1, 2, 3}, {4, 5, 4}}, m.Hands)
+	a.Equal(map[int]int{1: 1, 2: 2}, m.Counts)
+	a.Equal([]int{5}, m.Bottomed)
+	a.Equal([]int{4, 4}, m.Kept())
+}
+
+func TestGameNoMulligan(t *testing.T) {
+	a := assert.New(t)
+	m := &ArenaMulligans{Hands: [][]int{{1, 2}}}
+	a.Equal([]int{1, 2}, m.Kept())
+	a.Nil((&ArenaMulligans{}).Kept())
+}
+
+func TestLogMulligansStandalone(t *testing.T) {
+	a := assert.New(t)
+	// Arena sends the requests to the player in their own events
+	l := &bo3Log{}
+	l.segment(`<== Event.DeckSubmitV3(1)
+{"CourseDeck": {"id": "deck1", "mainDeck": [{"id": 1, "quantity": 4}]}}`)
+	l.segment(` (Incoming Event.MatchCreated)
+{"matchId": "m1", "opponentScreenName": "Opponent"}`)
+	l.segment(`{"greToClientEvent": {"greToClientMessages": [{"type": "GREMessageType_ConnectResp", "systemSeatIds": [1]}]}}`)
+	l.segment(`{"greToClientEvent": {"greToClientMessages": [{"type": "GREMessageType_DieRollResultsResp", "systemSeatIds": [1, 2],
+ "dieRollResultsResp": {"playerDieRolls": [{"systemSeatId": 1, "rollValue": 4}, {"systemSeatId": 2, "rollValue": 12}]}}]}}`)
+	l.segment(`{"greToClientEvent": {"greToClientMessages": [{"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+ "type": "GameStateType_Full", "gameStateId": 1,
+ "zones": [{"zoneId": 31, "type": "ZoneType_Hand", "ownerSeatId": 1, "objectInstanceIds": [160, 161]}],
+ "gameObjects": [
+  {"instanceId": 160, "grpId": 1, "type": "GameObjectType_Card", "zoneId": 31, "ownerSeatId": 1},
+  {"instanceId": 161, "grpId": 2, "type": "GameObjectType_Card", "zoneId": 31, "ownerSeatId": 1}]}}]}}`)
+	l.segment(`{"greToClientEvent": {"greToClientMessages": [{"type": "GREMessageType_MulliganReq", "systemSeatIds": [1], "gameStateId": 1,
+ "mulliganReq": {"mulliganType": "MulliganType_London"}}]}}`)
+	p := NewLogParser(nil)
+	_, err := p.Parse(l)
+	a.Nil(err)
+	p.Flush()
+	x := p.Log().Extract()
+	a.Len(x.Matches, 1)
+	g := x.Matches[0].Games[0]
+	a.Equal(1, g.PlayerSeat())
+	a.Equal(map[int]int{1: 4, 2: 12}, g.DieRolls)
+	a.NotNil(g.Mulligans)
+	a.Equal([][]int{{1, 2}}, g.Mulligans.Hands)
+}
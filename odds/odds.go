@@ -0,0 +1,181 @@
+package odds
+
+import (
+	"sort"
+
+	"github.com/gathering-gg/parser"
+)
+
+const zoneLibrary = "ZoneType_Library"
+
+// Library is what is left in a player's library. Cards are the copies of
+// each grpId we believe are still in it, Size is how many cards it has.
+// Cards that left the library without being seen (like a face down exile)
+// are still counted in Cards, so Size may be smaller than their total.
+type Library struct {
+	Cards map[int]int
+	Size  int
+}
+
+// Remaining works out the library from the deck and the player's cards the
+// game state shows outside of it: in hand, on the battlefield, in the
+// graveyard, exile and so on.
+func Remaining(deck *gathering.ArenaDeck, state *gathering.GameSnapshot, seat int) *Library {
+	lib := &Library{Cards: make(map[int]int)}
+	for _, c := range deck.MainDeck {
+		lib.Cards[c.ID] += c.Quantity
+		lib.Size += c.Quantity
+	}
+	known := false
+	for _, z := range state.Zones {
+		// Shared zones like the battlefield have no owner
+		if z.OwnerSeatID != 0 && z.OwnerSeatID != seat {
+			continue
+		}
+		if z.Type == zoneLibrary {
+			lib.Size = len(z.ObjectInstanceIDs)
+			known = true
+			continue
+		}
+		for _, id := range z.ObjectInstanceIDs {
+			o, ok := state.Objects[id]
+			if !ok || o.GrpID == 0 || o.Type != "GameObjectType_Card" || o.OwnerSeatID != seat {
+				continue
+			}
+			if lib.Cards[o.GrpID] > 0 {
+				lib.Cards[o.GrpID]--
+				if !known {
+					lib.Size--
+				}
+			}
+		}
+	}
+	for id, n := range lib.Cards {
+		if n == 0 {
+			delete(lib.Cards, id)
+		}
+	}
+	return lib
+}
+
+// Copies is how many of the cards are left, counting every copy of each
+func (l *Library) Copies(grpIDs ...int) int {
+	n := 0
+	for _, id := range grpIDs {
+		n += l.Cards[id]
+	}
+	if n > l.Size {
+		n = l.Size
+	}
+	return n
+}
+
+// Chance is the chance of drawing at least one of the cards in the next draws
+func (l *Library) Chance(draws int, grpIDs ...int) float64 {
+	return AtLeast(l.Size, l.Copies(grpIDs...), draws, 1)
+}
+
+// CardOdds is a card left in the library and the chance of drawing it
+type CardOdds struct {
+	GrpID    int     `json:"grpId"`
+	Copies   int     `json:"copies"`
+	NextDraw float64 `json:"nextDraw"`
+	ByTurn   float64 `json:"byTurn"`
+}
+
+// Composition lists the cards left, most copies first, with the chance of
+// drawing each in the next draw and within draws draws.
+func (l *Library) Composition(draws int) []CardOdds {
+	var cards []CardOdds
+	for id := range l.Cards {
+		cards = append(cards, CardOdds{
+			GrpID:    id,
+			Copies:   l.Copies(id),
+			NextDraw: l.Chance(1, id),
+			ByTurn:   l.Chance(draws, id),
+		})
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].Copies != cards[j].Copies {
+			return cards[i].Copies > cards[j].Copies
+		}
+		return cards[i].GrpID < cards[j].GrpID
+	})
+	return cards
+}
+
+// DrawsUntil is how many cards the player in seat draws for turn from now
+// up to and including turn. The player on the play skips the draw on turn 1.
+func DrawsUntil(state *gathering.GameSnapshot, seat, turn int) int {
+	info := state.TurnInfo
+	if info == nil {
+		return 0
+	}
+	draws := 0
+	active := info.ActivePlayer
+	for t := info.TurnNumber; t <= turn; t++ {
+		if active == seat && t > 1 && (t > info.TurnNumber || beforeDraw(info)) {
+			draws++
+		}
+		active = otherSeat(state, active)
+	}
+	return draws
+}
+
+// beforeDraw checks if the turn hasn't reached its draw step yet
+func beforeDraw(info *gathering.TurnInfo) bool {
+	return info.Phase == "" || info.Phase == "Phase_Beginning" && info.Step != "Step_Draw"
+}
+
+// otherSeat is the seat of the other player in a two player game
+func otherSeat(state *gathering.GameSnapshot, seat int) int {
+	for s := range state.Players {
+		if s != seat {
+			return s
+		}
+	}
+	return seat
+}
+
+// AtLeast is the hypergeometric chance of getting at least k successes in
+// draws draws from a population with successes in it.
+func AtLeast(population, successes, draws, k int) float64 {
+	if draws > population {
+		draws = population
+	}
+	if k <= 0 {
+		return 1
+	}
+	p := 0.0
+	for i := k; i <= draws && i <= successes; i++ {
+		p += Exactly(population, successes, draws, i)
+	}
+	if p > 1 {
+		p = 1
+	}
+	return p
+}
+
+// Exactly is the hypergeometric chance of getting exactly k successes in
+// draws draws from a population with successes in it.
+func Exactly(population, successes, draws, k int) float64 {
+	if k < 0 || k > successes || k > draws || draws-k > population-successes {
+		return 0
+	}
+	return choose(successes, k) * choose(population-successes, draws-k) / choose(population, draws)
+}
+
+// choose is the binomial coefficient, as a float so decks don't overflow
+func choose(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	if k > n-k {
+		k = n - k
+	}
+	c := 1.0
+	for i := 1; i <= k; i++ {
+		c = c * float64(n-k+i) / float64(i)
+	}
+	return c
+}
@@ -0,0 +1,81 @@
+package odds
+
+import (
+	"testing"
+
+	"github.com/gathering-gg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtLeast(t *testing.T) {
+	a := assert.New(t)
+	// A 4 of in the opening hand
+	a.InDelta(0.3995, AtLeast(60, 4, 7, 1), 0.0001)
+	a.InDelta(0.0632, AtLeast(60, 4, 7, 2), 0.0001)
+	a.Equal(1.0, AtLeast(60, 4, 7, 0))
+	a.Equal(0.0, AtLeast(60, 0, 7, 1))
+	a.InDelta(1.0, AtLeast(3, 3, 5, 3), 0.0001)
+	a.InDelta(0.5, Exactly(2, 1, 1, 1), 0.0001)
+}
+
+func testState() *gathering.GameSnapshot {
+	g := gathering.NewGameState()
+	return g.Apply(&gathering.GameStateMessage{
+		Type:     gathering.GameStateFull,
+		TurnInfo: &gathering.TurnInfo{TurnNumber: 3, ActivePlayer: 1, Phase: "Phase_Main1"},
+		Players:  []gathering.PlayerState{{SystemSeatNumber: 1}, {SystemSeatNumber: 2}},
+		Zones: []gathering.Zone{
+			{ZoneID: 31, Type: "ZoneType_Hand", OwnerSeatID: 1, ObjectInstanceIDs: []int{1, 2}},
+			{ZoneID: 32, Type: "ZoneType_Library", OwnerSeatID: 1, ObjectInstanceIDs: make([]int, 8)},
+			{ZoneID: 28, Type: "ZoneType_Battlefield", ObjectInstanceIDs: []int{3, 4}},
+		},
+		GameObjects: []gathering.ArenaMatchGameObject{
+			{InstanceID: 1, GrpID: 100, Type: "GameObjectType_Card", OwnerSeatID: 1},
+			{InstanceID: 2, GrpID: 200, Type: "GameObjectType_Card", OwnerSeatID: 1},
+			{InstanceID: 3, GrpID: 100, Type: "GameObjectType_Card", OwnerSeatID: 1},
+			{InstanceID: 4, GrpID: 100, Type: "GameObjectType_Card", OwnerSeatID: 2},
+		},
+	})
+}
+
+func TestRemaining(t *testing.T) {
+	a := assert.New(t)
+	deck := &gathering.ArenaDeck{MainDeck: []gathering.ArenaDeckCard{
+		{ID: 100, Quantity: 4},
+		{ID: 200, Quantity: 1},
+		{ID: 300, Quantity: 6},
+	}}
+	lib := Remaining(deck, testState(), 1)
+	a.Equal(map[int]int{100: 2, 300: 6}, lib.Cards)
+	a.Equal(8, lib.Size)
+	a.Equal(8, lib.Copies(100, 300))
+	a.InDelta(0.25, lib.Chance(1, 100), 0.0001)
+	a.Equal(1.0, lib.Chance(1, 100, 300))
+	c := lib.Composition(2)
+	a.Equal([]CardOdds{
+		{GrpID: 300, Copies: 6, NextDraw: 0.75, ByTurn: AtLeast(8, 6, 2, 1)},
+		{GrpID: 100, Copies: 2, NextDraw: 0.25, ByTurn: AtLeast(8, 2, 2, 1)},
+	}, c)
+}
+
+func TestRemainingUnknownLibrary(t *testing.T) {
+	a := assert.New(t)
+	deck := &gathering.ArenaDeck{MainDeck: []gathering.ArenaDeckCard{{ID: 100, Quantity: 4}}}
+	lib := Remaining(deck, testState(), 2)
+	// Only the opponent's copy on the battlefield has left their library
+	a.Equal(map[int]int{100: 3}, lib.Cards)
+	a.Equal(3, lib.Size)
+}
+
+func TestDrawsUntil(t *testing.T) {
+	a := assert.New(t)
+	state := testState()
+	// Seat 1 is in their main phase on turn 3 and has drawn already
+	a.Equal(0, DrawsUntil(state, 1, 3))
+	a.Equal(1, DrawsUntil(state, 1, 5))
+	a.Equal(1, DrawsUntil(state, 2, 4))
+	a.Equal(2, DrawsUntil(state, 2, 6))
+	state.TurnInfo = &gathering.TurnInfo{TurnNumber: 1, ActivePlayer: 1, Phase: "Phase_Beginning", Step: "Step_Upkeep"}
+	a.Equal(0, DrawsUntil(state, 1, 1))
+	a.Equal(1, DrawsUntil(state, 1, 3))
+}
@@ -0,0 +1,181 @@
+package gathering
+
+import (
+	"sort"
+)
+
+// colorOrder is the order colors are listed in, with the letter for each
+var colorOrder = []struct {
+	Color  string
+	Letter string
+}{
+	{"CardColor_White", "W"},
+	{"CardColor_Blue", "U"},
+	{"CardColor_Black", "B"},
+	{"CardColor_Red", "R"},
+	{"CardColor_Green", "G"},
+}
+
+// OpponentCard is a card the opponent was seen with. MinCopies is the most
+// copies seen in one game, they may have more. Zones are where it was seen.
+type OpponentCard struct {
+	GrpID     int      `json:"grpId"`
+	MinCopies int      `json:"minCopies"`
+	Zones     []string `json:"zones"`
+}
+
+// OpponentDeck is everything we saw of the opponent's deck over a match.
+// Colors are the colors of their cards, as letters in WUBRG order.
+type OpponentDeck struct {
+	Cards  []OpponentCard `json:"cards"`
+	Colors []string       `json:"colors"`
+}
+
+// Count is the number of cards seen, counting copies
+func (d *OpponentDeck) Count() int {
+	n := 0
+	for _, c := range d.Cards {
+		n += c.MinCopies
+	}
+	return n
+}
+
+// PlayerSeat is the player's seat in the game, or 0 if we don't know it
+func (g *ArenaGame) PlayerSeat() int {
+	if g.SeatID != nil {
+		return *g.SeatID
+	}
+	return g.seat
+}
+
+// shownCards are the cards of each seat the GRE described in a game,
+// collected as its messages are applied. Zones keep their type for the whole
+// game, so zone ids are only turned into types when asked for.
+type shownCards struct {
+	zoneTypes map[int]string
+	seats     map[int]*seatCards
+}
+
+// seatCards are the cards seen of one seat. Instances and zones are keyed by
+// grpId, order has the grpIds in the order they were first seen.
+type seatCards struct {
+	order     []int
+	instances map[int]map[int]bool
+	zones     map[int]map[int]bool
+	colors    map[string]bool
+}
+
+// shownCards are the cards seen so far in the game
+func (g *ArenaGame) shownCards() *shownCards {
+	if g.shown == nil {
+		g.shown = &shownCards{
+			zoneTypes: make(map[int]string),
+			seats:     make(map[int]*seatCards),
+		}
+	}
+	return g.shown
+}
+
+// add records the cards in a message
+func (c *shownCards) add(msg *GameStateMessage) {
+	for _, z := range msg.Zones {
+		c.zoneTypes[z.ZoneID] = z.Type
+	}
+	for _, o := range msg.GameObjects {
+		if o.OwnerSeatID == 0 || o.GrpID == 0 || o.Type != "GameObjectType_Card" {
+			continue
+		}
+		seat := c.seats[o.OwnerSeatID]
+		if seat == nil {
+			seat = &seatCards{
+				instances: make(map[int]map[int]bool),
+				zones:     make(map[int]map[int]bool),
+				colors:    make(map[string]bool),
+			}
+			c.seats[o.OwnerSeatID] = seat
+		}
+		if seat.instances[o.GrpID] == nil {
+			seat.instances[o.GrpID] = make(map[int]bool)
+			seat.zones[o.GrpID] = make(map[int]bool)
+			seat.order = append(seat.order, o.GrpID)
+		}
+		seat.instances[o.GrpID][o.InstanceID] = true
+		seat.zones[o.GrpID][o.ZoneID] = true
+		for _, color := range o.Color {
+			seat.colors[color] = true
+		}
+	}
+}
+
+// InferOpponentDeck collects the opponent's cards from every game of the
+// match. Copies are told apart by following them across instance ids, so two
+// copies on the battlefield count as two.
+func (a *ArenaMatch) InferOpponentDeck() *OpponentDeck {
+	copies := make(map[int]int)
+	zones := make(map[int]map[string]bool)
+	colors := make(map[string]bool)
+	var order []int
+	for _, g := range a.Games {
+		seat := g.PlayerSeat()
+		if seat == 0 || g.shown == nil {
+			continue
+		}
+		// Go through the seats in order so the cards are listed the same
+		// way every time
+		var seats []int
+		for owner := range g.shown.seats {
+			if owner != seat {
+				seats = append(seats, owner)
+			}
+		}
+		sort.Ints(seats)
+		for _, owner := range seats {
+			shown := g.shown.seats[owner]
+			for _, grpID := range shown.order {
+				// Instance ids of the same card may only have been linked
+				// after they were seen
+				cards := make(map[int]bool)
+				for instanceID := range shown.instances[grpID] {
+					card := instanceID
+					if c := g.Lineage().Card(instanceID); c != nil {
+						card = c.ID
+					}
+					cards[card] = true
+				}
+				if len(cards) > copies[grpID] {
+					copies[grpID] = len(cards)
+				}
+				if zones[grpID] == nil {
+					zones[grpID] = make(map[string]bool)
+					order = append(order, grpID)
+				}
+				for zoneID := range shown.zones[grpID] {
+					if z, ok := g.shown.zoneTypes[zoneID]; ok {
+						zones[grpID][z] = true
+					}
+				}
+			}
+			for color := range shown.colors {
+				colors[color] = true
+			}
+		}
+	}
+	deck := &OpponentDeck{
+		Cards:  []OpponentCard{},
+		Colors: []string{},
+	}
+	for _, grpID := range order {
+		card := OpponentCard{GrpID: grpID, MinCopies: copies[grpID], Zones: []string{}}
+		for z := range zones[grpID] {
+			card.Zones = append(card.Zones, z)
+		}
+		sort.Strings(card.Zones)
+		deck.Cards = append(deck.Cards, card)
+	}
+	for _, c := range colorOrder {
+		if colors[c.Color] {
+			deck.Colors = append(deck.Colors, c.Letter)
+		}
+	}
+	return deck
+}
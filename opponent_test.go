@@ -0,0 +1,89 @@
+package gathering
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOpponentGame(seat int, objects ...ArenaMatchGameObject) *ArenaGame {
+	g := &ArenaGame{SeatID: &seat}
+	msg := &GameStateMessage{
+		Type:        GameStateFull,
+		GameStateID: 1,
+		Zones: []Zone{
+			{ZoneID: 28, Type: "ZoneType_Battlefield"},
+			{ZoneID: 33, Type: "ZoneType_Graveyard", OwnerSeatID: 2},
+		},
+		GameObjects: objects,
+	}
+	g.State().Apply(msg)
+	g.shownCards().add(msg)
+	return g
+}
+
+func opponentCard(id, grpID, zone int, colors ...string) ArenaMatchGameObject {
+	return ArenaMatchGameObject{
+		InstanceID:  id,
+		GrpID:       grpID,
+		Type:        "GameObjectType_Card",
+		ZoneID:      zone,
+		OwnerSeatID: 2,
+		Color:       colors,
+	}
+}
+
+func TestInferOpponentDeck(t *testing.T) {
+	a := assert.New(t)
+	game1 := testOpponentGame(1,
+		opponentCard(200, 10, 28, "CardColor_Red"),
+		opponentCard(201, 10, 28, "CardColor_Red"),
+		opponentCard(202, 11, 33, "CardColor_Green"),
+		ArenaMatchGameObject{InstanceID: 100, GrpID: 12, Type: "GameObjectType_Card", ZoneID: 28, OwnerSeatID: 1, Color: []string{"CardColor_Blue"}},
+		ArenaMatchGameObject{InstanceID: 203, GrpID: 13, Type: "GameObjectType_Token", ZoneID: 28, OwnerSeatID: 2},
+	)
+	// 300 became 301 when it moved, it is one card
+	game2 := testOpponentGame(1,
+		opponentCard(300, 10, 28, "CardColor_Red"),
+		opponentCard(301, 10, 33, "CardColor_Red"),
+	)
+	game2.Lineage().Link(300, 301)
+	match := &ArenaMatch{Games: []*ArenaGame{game1, game2}}
+	deck := match.InferOpponentDeck()
+	a.Equal([]string{"R", "G"}, deck.Colors)
+	a.Len(deck.Cards, 2)
+	cards := make(map[int]OpponentCard)
+	for _, c := range deck.Cards {
+		cards[c.GrpID] = c
+	}
+	a.Equal(2, cards[10].MinCopies)
+	a.Equal([]string{"ZoneType_Battlefield", "ZoneType_Graveyard"}, cards[10].Zones)
+	a.Equal(1, cards[11].MinCopies)
+	a.Equal([]string{"ZoneType_Graveyard"}, cards[11].Zones)
+	a.Equal(3, deck.Count())
+}
+
+func TestInferOpponentDeckUnknownSeat(t *testing.T) {
+	a := assert.New(t)
+	g := testOpponentGame(0, opponentCard(200, 10, 28))
+	g.SeatID = nil
+	deck := (&ArenaMatch{Games: []*ArenaGame{g}}).InferOpponentDeck()
+	a.Len(deck.Cards, 0)
+}
+
+func TestInferOpponentDeckOrder(t *testing.T) {
+	a := assert.New(t)
+	var objects []ArenaMatchGameObject
+	for i := 0; i < 20; i++ {
+		objects = append(objects, opponentCard(200+i, 50-i, 28))
+	}
+	match := &ArenaMatch{Games: []*ArenaGame{testOpponentGame(1, objects...)}}
+	first := match.InferOpponentDeck()
+	a.Len(first.Cards, 20)
+	// Seen in instanceId order
+	a.Equal(50, first.Cards[0].GrpID)
+	a.Equal(31, first.Cards[19].GrpID)
+	for i := 0; i < 20; i++ {
+		a.Equal(first, match.InferOpponentDeck())
+	}
+}
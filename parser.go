@@ -0,0 +1,346 @@
+package gathering
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// checkpointTailSize is how many bytes before a checkpoint are remembered to
+// detect the log being rewritten underneath us.
+const checkpointTailSize = 64
+
+// DefaultMaxLineSize is the longest line a LogParser reads unless told
+// otherwise. Collections and deck lists are a single line in some versions of
+// the log, so this is deliberately generous.
+const DefaultMaxLineSize = 64 << 20
+
+// LineTooLongError is recorded in Log.Errors when a line is longer than the
+// parser allows. The line is left out of its segment.
+type LineTooLongError struct {
+	Line  int
+	Size  int
+	Limit int
+}
+
+func (e *LineTooLongError) Error() string {
+	return fmt.Sprintf("line %d is %d bytes, longer than the limit of %d", e.Line, e.Size, e.Limit)
+}
+
+// Checkpoint is a serializable position in a log file. A LogParser created
+// from a Checkpoint resumes at the start of the segment that was still open
+// when the Checkpoint was taken, so nothing is lost or parsed twice.
+// The Log of a resumed parser starts with the Segments kept in the
+// Checkpoint, usually an Extractor's Kept segments. Those are enough to
+// extract the collection, inventory, decks, player name and rank, and to
+// carry on with the deck last joined and a match being played. Everything
+// else before the Checkpoint is gone: the other segments, their errors and
+// calls, opened boosters, prizes claimed and matches that had finished.
+type Checkpoint struct {
+	Offset    int64          `json:"offset"`
+	Line      int            `json:"line"`
+	Tail      []byte         `json:"tail"`
+	DateOrder DateOrder      `json:"dateOrder"`
+	Segments  []SavedSegment `json:"segments"`
+}
+
+// SavedSegment is a Segment as kept in a Checkpoint. What it is, and its
+// payload, are worked out again when it is restored.
+type SavedSegment struct {
+	LoggerType LoggerType `json:"loggerType"`
+	Time       *time.Time `json:"time"`
+	Range      []int      `json:"range"`
+	Line       string     `json:"line"`
+	Text       string     `json:"text"`
+}
+
+// Keep saves segments in the checkpoint, replacing any kept before
+func (cp *Checkpoint) Keep(segments []*Segment) {
+	cp.Segments = nil
+	for _, s := range segments {
+		cp.Segments = append(cp.Segments, SavedSegment{
+			LoggerType: s.LoggerType,
+			Time:       s.Time,
+			Range:      s.Range,
+			Line:       string(s.Line),
+			Text:       string(s.Text),
+		})
+	}
+}
+
+// Restore returns the kept segments, classified and decoded again
+func (cp *Checkpoint) Restore() []*Segment {
+	var segments []*Segment
+	for _, saved := range cp.Segments {
+		s := &Segment{
+			LoggerType: saved.LoggerType,
+			Time:       saved.Time,
+			Range:      saved.Range,
+			Line:       []byte(saved.Line),
+			Text:       []byte(saved.Text),
+		}
+		s.Method = segmentMethod(s)
+		s.SegmentType, s.Types = segmentType(s)
+		decodeRegistered(s)
+		segments = append(segments, s)
+	}
+	return segments
+}
+
+// LogParser incrementally parses a log into Segments. Every call to Parse
+// consumes only the bytes appended since the last call. The last segment in
+// the log is kept open until the next segment header (or Flush) finishes it,
+// since Arena may still be writing to it.
+// Lines of any length are read, up to MaxLineSize (DefaultMaxLineSize when 0).
+// Timestamps are read in the Location time zone (time.Local when nil), with
+// the day and month in the order given by DateOrder, or worked out from the
+// log when it is DateOrderAuto. Segments without a date in their header take
+// the timestamp in their payload, or one between their neighbours. Those
+// between the last dated segment and the end of the data are only filled in
+// once a later segment, or Flush, gives them one. Times guessed before the
+// date order was known are fixed in place, so segments already returned see
+// the fix.
+// When Discard is set, the Log only has the segments and errors of the last
+// call to Parse or Flush, so a parser that runs for a whole session doesn't
+// hold on to the whole log. Feed what Parse returns to an Extractor instead.
+type LogParser struct {
+	MaxLineSize int
+	Location    *time.Location
+	DateOrder   DateOrder
+	Discard     bool
+
+	log       *Log
+	offset    int64
+	line      int
+	start     int64
+	startLine int
+	tail      []byte
+	partial   []byte
+	lineSize  int
+	skipping  bool
+	pending   *Segment
+	buffer    bytes.Buffer
+	order     DateOrder
+	undated   []*Segment
+	untimed   []*Segment
+	lastTime  *time.Time
+
+	guessed     []guessedTime
+	guessedFrom *time.Time
+}
+
+// NewLogParser creates a parser. If a checkpoint is given, parsing resumes
+// from it with the segments it kept, otherwise it starts at the beginning of
+// the log.
+func NewLogParser(cp *Checkpoint) *LogParser {
+	p := &LogParser{}
+	p.reset(cp)
+	return p
+}
+
+func (p *LogParser) reset(cp *Checkpoint) {
+	p.log = &Log{}
+	p.offset = 0
+	p.line = 0
+	p.tail = nil
+	p.partial = nil
+	p.lineSize = 0
+	p.skipping = false
+	p.pending = nil
+	p.buffer.Reset()
+	p.undated = nil
+	p.untimed = nil
+	p.lastTime = nil
+	p.guessed = nil
+	p.guessedFrom = nil
+	if cp != nil {
+		p.offset = cp.Offset
+		p.line = cp.Line
+		p.tail = cp.Tail
+		p.order = cp.DateOrder
+		p.log.Segments = cp.Restore()
+	}
+	p.start = p.offset
+	p.startLine = p.line
+	if p.offset == 0 {
+		// Anything before the first header is kept as its own segment
+		p.pending = &Segment{
+			Range: []int{0},
+		}
+	}
+}
+
+// Log returns everything parsed so far
+func (p *LogParser) Log() *Log {
+	return p.log
+}
+
+// Checkpoint returns the position parsing can be resumed from
+func (p *LogParser) Checkpoint() Checkpoint {
+	return Checkpoint{
+		Offset:    p.start,
+		Line:      p.startLine,
+		Tail:      p.tail,
+		DateOrder: p.order,
+	}
+}
+
+// Parse reads r until EOF and returns the segments finished by the new data.
+// r must continue exactly where the previous call left off.
+func (p *LogParser) Parse(r io.Reader) ([]*Segment, error) {
+	p.discard()
+	n := len(p.log.Segments)
+	reader := bufio.NewReader(r)
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		p.offset += int64(len(chunk))
+		p.lineSize += len(chunk)
+		if p.lineSize > p.maxLineSize() {
+			p.partial = nil
+			p.skipping = true
+		} else {
+			p.partial = append(p.partial, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF {
+			// Arena hasn't finished writing this line yet
+			break
+		}
+		if err != nil {
+			return p.log.Segments[n:], err
+		}
+		p.line++
+		p.endLine()
+	}
+	return p.log.Segments[n:], nil
+}
+
+// ParseFile parses what has been appended to f since the last call. If the
+// file was truncated or rewritten (e.g. the Arena client restarted), the
+// parser starts over with a new Log and truncated is true.
+func (p *LogParser) ParseFile(f *os.File) (segments []*Segment, truncated bool, err error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if fi.Size() < p.offset || !p.verify(f) {
+		truncated = true
+		p.reset(nil)
+	}
+	if _, err = f.Seek(p.offset, io.SeekStart); err != nil {
+		return nil, truncated, err
+	}
+	segments, err = p.Parse(f)
+	p.tail = readTail(f, p.start)
+	return segments, truncated, err
+}
+
+// Flush finishes the trailing segment and adds it to the log. Only call this
+// once no more data is expected, such as when parsing a closed log.
+func (p *LogParser) Flush() *Segment {
+	p.discard()
+	if p.lineSize > 0 {
+		p.line++
+		p.endLine()
+	}
+	s := p.finish(p.line + 1)
+	p.fillTrailing()
+	p.start = p.offset
+	p.startLine = p.line
+	return s
+}
+
+// discard drops what the last call added to the Log, if the parser is set
+// to Discard it
+func (p *LogParser) discard() {
+	if p.Discard {
+		p.log.Segments = nil
+		p.log.Errors = nil
+	}
+}
+
+func (p *LogParser) maxLineSize() int {
+	if p.MaxLineSize > 0 {
+		return p.MaxLineSize
+	}
+	return DefaultMaxLineSize
+}
+
+// endLine handles the line that was just completed
+func (p *LogParser) endLine() {
+	if p.skipping {
+		p.log.Errors = append(p.log.Errors, &LineTooLongError{
+			Line:  p.line,
+			Size:  p.lineSize,
+			Limit: p.maxLineSize(),
+		})
+	} else {
+		p.consume(p.partial, p.offset-int64(p.lineSize))
+	}
+	p.partial = nil
+	p.lineSize = 0
+	p.skipping = false
+}
+
+func (p *LogParser) consume(b []byte, at int64) {
+	if !segmentStartRegex.Match(b) {
+		p.buffer.Write(b)
+		return
+	}
+	t := UnityLogger
+	if bytes.Contains(b, clientGRE) {
+		t = ClientGRE
+	}
+	p.finish(p.line)
+	p.start = at
+	p.startLine = p.line - 1
+	p.pending = &Segment{
+		LoggerType: t,
+		Line:       b,
+		Range:      []int{p.line},
+	}
+	p.setTime(p.pending)
+}
+
+// finish completes the pending segment, ending it at line end
+func (p *LogParser) finish(end int) *Segment {
+	s := p.pending
+	p.pending = nil
+	if s == nil {
+		p.buffer.Reset()
+		return nil
+	}
+	s.Text = make([]byte, p.buffer.Len())
+	copy(s.Text, p.buffer.Bytes())
+	p.buffer.Reset()
+	s.Range = append(s.Range, end)
+	s.Method = segmentMethod(s)
+	s.SegmentType, s.Types = segmentType(s)
+	p.log.Errors = append(p.log.Errors, decodeRegistered(s)...)
+	p.fillTime(s)
+	p.log.Segments = append(p.log.Segments, s)
+	return s
+}
+
+// verify checks that the bytes before the checkpoint haven't changed
+func (p *LogParser) verify(f *os.File) bool {
+	if len(p.tail) == 0 {
+		return true
+	}
+	return bytes.Equal(p.tail, readTail(f, p.start))
+}
+
+func readTail(f *os.File, offset int64) []byte {
+	from := offset - checkpointTailSize
+	if from < 0 {
+		from = 0
+	}
+	b := make([]byte, offset-from)
+	n, _ := f.ReadAt(b, from)
+	return b[:n]
+}
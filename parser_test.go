@@ -0,0 +1,150 @@
+package gathering
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseWhole(t *testing.T, b []byte) *Log {
+	p := NewLogParser(nil)
+	_, err := p.Parse(bytes.NewReader(b))
+	assert.Nil(t, err)
+	p.Flush()
+	return p.Log()
+}
+
+func TestLogParserIncremental(t *testing.T) {
+	a := assert.New(t)
+	b, err := ioutil.ReadFile("test/march-constructed.txt")
+	a.Nil(err)
+	expected := parseWhole(t, b)
+	p := NewLogParser(nil)
+	// Uneven chunks so lines and segments are split at odd places
+	for i := 0; i < len(b); i += 997 {
+		end := i + 997
+		if end > len(b) {
+			end = len(b)
+		}
+		_, err := p.Parse(bytes.NewReader(b[i:end]))
+		a.Nil(err)
+	}
+	p.Flush()
+	a.Equal(expected.Segments, p.Log().Segments)
+}
+
+func TestLogParserOpenSegment(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	segments, err := p.Parse(bytes.NewBufferString("[UnityCrossThreadLogger]4/2/2019 3:01:51 PM\n<== Event.ClaimPrize(298)\n{\n"))
+	a.Nil(err)
+	// Only the text before the first header is finished
+	a.Len(segments, 1)
+	segments, err = p.Parse(bytes.NewBufferString("}\n[UnityCrossThreadLogger]4/2/2019 3:01:52 PM\n"))
+	a.Nil(err)
+	a.Len(segments, 1)
+	a.Equal("<== Event.ClaimPrize(298)\n{\n}\n", string(segments[0].Text))
+	a.Equal([]int{1, 5}, segments[0].Range)
+	a.True(segments[0].IsClaimPrize())
+}
+
+func TestLogParserCheckpoint(t *testing.T) {
+	a := assert.New(t)
+	b, err := ioutil.ReadFile("test/march-constructed.txt")
+	a.Nil(err)
+	expected := parseWhole(t, b)
+	half := len(b) / 2
+	first := NewLogParser(nil)
+	_, err = first.Parse(bytes.NewReader(b[:half]))
+	a.Nil(err)
+	data, err := json.Marshal(first.Checkpoint())
+	a.Nil(err)
+	var cp Checkpoint
+	a.Nil(json.Unmarshal(data, &cp))
+	second := NewLogParser(&cp)
+	_, err = second.Parse(bytes.NewReader(b[cp.Offset:]))
+	a.Nil(err)
+	second.Flush()
+	segments := append(first.Log().Segments, second.Log().Segments...)
+	a.Equal(expected.Segments, segments)
+}
+
+func TestLogParserTruncated(t *testing.T) {
+	a := assert.New(t)
+	f, err := ioutil.TempFile(os.TempDir(), "gathering-test-")
+	a.Nil(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	p := NewLogParser(nil)
+	f.WriteString("[UnityCrossThreadLogger]4/2/2019 3:01:51 PM\nfirst\n[UnityCrossThreadLogger]4/2/2019 3:01:52 PM\nsecond\n")
+	segments, truncated, err := p.ParseFile(f)
+	a.Nil(err)
+	a.False(truncated)
+	a.Len(segments, 2)
+	f.WriteString("more\n")
+	segments, truncated, err = p.ParseFile(f)
+	a.Nil(err)
+	a.False(truncated)
+	a.Len(segments, 0)
+	// Arena restarted and wrote a new log that is already longer than the old one
+	f.Truncate(0)
+	f.WriteAt([]byte("[UnityCrossThreadLogger]4/3/2019 9:00:00 AM\nrestarted, with a much longer line than before\n[UnityCrossThreadLogger]4/3/2019 9:00:01 AM\n"), 0)
+	segments, truncated, err = p.ParseFile(f)
+	a.Nil(err)
+	a.True(truncated)
+	a.Len(segments, 2)
+	a.Len(p.Log().Segments, 2)
+	a.Equal("restarted, with a much longer line than before\n", string(segments[1].Text))
+}
+
+func TestLogParserLongLine(t *testing.T) {
+	a := assert.New(t)
+	collection := `{"67692": 4` + strings.Repeat(`, "67692": 4`, 50000) + "}\n"
+	p := NewLogParser(nil)
+	_, err := p.Parse(bytes.NewBufferString("[UnityCrossThreadLogger]4/2/2019 3:01:51 PM\n<== PlayerInventory.GetPlayerCardsV3(300)\n" + collection + "[UnityCrossThreadLogger]4/2/2019 3:01:52 PM\n"))
+	a.Nil(err)
+	a.Empty(p.Log().Errors)
+	s := p.Log().Segments[1]
+	a.True(s.IsCollection())
+	col, err := s.ParseCollection()
+	a.Nil(err)
+	a.Equal(4, col["67692"])
+}
+
+func TestLogParserMaxLineSize(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	p.MaxLineSize = 10000
+	long := strings.Repeat("x", 20000) + "\n"
+	_, err := p.Parse(bytes.NewBufferString("[UnityCrossThreadLogger]4/2/2019 3:01:51 PM\nbefore\n" + long + "after\n[UnityCrossThreadLogger]4/2/2019 3:01:52 PM\n"))
+	a.Nil(err)
+	a.Len(p.Log().Errors, 1)
+	tooLong, ok := p.Log().Errors[0].(*LineTooLongError)
+	a.True(ok)
+	a.Equal(3, tooLong.Line)
+	a.Equal(20001, tooLong.Size)
+	a.Equal("before\nafter\n", string(p.Log().Segments[1].Text))
+}
+
+func TestLogParserDiscard(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	p.Discard = true
+	segments, err := p.Parse(bytes.NewBufferString("[UnityCrossThreadLogger]4/2/2019 3:01:51 PM\nfirst\n[UnityCrossThreadLogger]4/2/2019 3:01:52 PM\nsecond\n"))
+	a.Nil(err)
+	a.Len(segments, 2)
+	a.Equal(segments, p.Log().Segments)
+	segments, err = p.Parse(bytes.NewBufferString("[UnityCrossThreadLogger]4/2/2019 3:01:53 PM\nthird\n"))
+	a.Nil(err)
+	a.Len(segments, 1)
+	a.Equal("second\n", string(segments[0].Text))
+	a.Equal(segments, p.Log().Segments)
+	s := p.Flush()
+	a.Equal("third\n", string(s.Text))
+	a.Equal([]*Segment{s}, p.Log().Segments)
+}
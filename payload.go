@@ -0,0 +1,127 @@
+package gathering
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Payload is a JSON value found in the text of a segment. Offset is where the
+// value starts in Segment.Text.
+type Payload struct {
+	Offset int
+	Raw    json.RawMessage
+}
+
+// PayloadError is returned when a payload was found in a segment, but could
+// not be decoded into what we expected.
+type PayloadError struct {
+	Offset int
+	Err    error
+}
+
+func (e *PayloadError) Error() string {
+	return fmt.Sprintf("payload at byte %d: %v", e.Offset, e.Err)
+}
+
+// Payloads returns every top level JSON object or array in the segment text,
+// in the order they appear.
+func (s *Segment) Payloads() []Payload {
+	var payloads []Payload
+	for _, p := range extractJSON(s.Text) {
+		if json.Valid(p.Raw) {
+			payloads = append(payloads, p)
+		}
+	}
+	return payloads
+}
+
+// firstPayload returns the first valid JSON value in the segment text
+func (s *Segment) firstPayload() (Payload, bool) {
+	for _, p := range extractJSON(s.Text) {
+		if json.Valid(p.Raw) {
+			return p, true
+		}
+	}
+	return Payload{}, false
+}
+
+// decodeObject decodes the first JSON object in the segment into v
+func (s *Segment) decodeObject(v interface{}) error {
+	return s.decode('{', v)
+}
+
+// decodeArray decodes the first JSON array in the segment into v
+func (s *Segment) decodeArray(v interface{}) error {
+	return s.decode('[', v)
+}
+
+// decode decodes the first value of the kind, '{' or '[', into v. A kind of 0
+// takes either. Spans that aren't JSON at all, like `[UnityCrossThreadLogger]`,
+// are skipped, and their error is only returned when nothing else decodes.
+func (s *Segment) decode(kind byte, v interface{}) error {
+	var invalid error
+	for _, p := range extractJSON(s.Text) {
+		if kind != 0 && p.Raw[0] != kind {
+			continue
+		}
+		err := json.Unmarshal(p.Raw, v)
+		if _, ok := err.(*json.SyntaxError); ok {
+			if invalid == nil {
+				invalid = &PayloadError{Offset: p.Offset, Err: err}
+			}
+			continue
+		}
+		if err != nil {
+			return &PayloadError{Offset: p.Offset, Err: err}
+		}
+		return nil
+	}
+	if invalid != nil {
+		return invalid
+	}
+	// No payload, which reads as an empty document
+	return json.Unmarshal(nil, v)
+}
+
+// extractJSON finds the top level bracketed spans in b in a single pass,
+// without checking they are valid JSON. Brackets inside strings are ignored.
+// When a span is never closed, like a payload cut off by a long line, the
+// outermost spans that did close inside it are returned instead.
+func extractJSON(b []byte) []Payload {
+	var payloads []Payload
+	var open []int
+	inString := false
+	escaped := false
+	for i, c := range b {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			// Quotes around a payload aren't part of it
+			inString = len(open) > 0
+		case '{', '[':
+			open = append(open, i)
+		case '}', ']':
+			if len(open) == 0 {
+				continue
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			// Replace the spans found inside this one
+			for len(payloads) > 0 && payloads[len(payloads)-1].Offset > start {
+				payloads = payloads[:len(payloads)-1]
+			}
+			payloads = append(payloads, Payload{Offset: start, Raw: json.RawMessage(b[start : i+1])})
+		}
+	}
+	return payloads
+}
@@ -0,0 +1,90 @@
+package gathering
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractJSONBracketsInStrings(t *testing.T) {
+	a := assert.New(t)
+	s := &Segment{
+		Text: []byte(`<== Deck.GetDeckListsV3(9)
+[{"id": "a", "name": "[Mono] {Red} \"Aggro]\""}] [UnityCrossThreadLogger]`),
+	}
+	payloads := s.Payloads()
+	a.Len(payloads, 1)
+	a.Equal(27, payloads[0].Offset)
+	decks, err := s.ParseArenaDecks()
+	a.Nil(err)
+	a.Len(decks, 1)
+	a.Equal(`[Mono] {Red} "Aggro]"`, decks[0].Name)
+}
+
+func TestExtractJSONMultiple(t *testing.T) {
+	a := assert.New(t)
+	s := &Segment{
+		Text: []byte(`(-1) Incoming Rank.Updated [1, 2] {"playerId": "abc", "newClass": "Gold"}
+(Filename: C:\buildslave\unity\build\Runtime/Export/Debug.bindings.h Line: 43)
+{"second": true}`),
+	}
+	payloads := s.Payloads()
+	a.Len(payloads, 3)
+	a.Equal(`[1, 2]`, string(payloads[0].Raw))
+	a.Equal(`{"second": true}`, string(payloads[2].Raw))
+	// The object is decoded, not the array in front of it
+	update, err := s.ParseRankUpdated()
+	a.Nil(err)
+	a.Equal("abc", update.PlayerID)
+	a.Equal("Gold", update.NewClass)
+}
+
+func TestExtractJSONUnclosed(t *testing.T) {
+	a := assert.New(t)
+	s := &Segment{
+		Text: []byte(`[Client GRE]GREConnection.HandleWebSocketClosed(reason "{"Details":{}}") {"unclosed": [`),
+	}
+	payloads := s.Payloads()
+	a.Len(payloads, 1)
+	a.Equal(`{"Details":{}}`, string(payloads[0].Raw))
+}
+
+func TestPayloadError(t *testing.T) {
+	a := assert.New(t)
+	s := &Segment{
+		Text: []byte(`<== PlayerInventory.GetPlayerInventory(14) {"gold": "lots"}`),
+	}
+	_, err := s.ParsePlayerInventory()
+	a.NotNil(err)
+	perr, ok := err.(*PayloadError)
+	a.True(ok)
+	a.Equal(43, perr.Offset)
+}
+
+func TestExtractJSONTruncated(t *testing.T) {
+	a := assert.New(t)
+	// Cut off partway, the values that did finish are still found
+	s := &Segment{Text: []byte(`{"greToClientEvent": {"a": {"b": 1}, "c": [1, 2], "d": {"e": [`)}
+	payloads := s.Payloads()
+	a.Len(payloads, 2)
+	a.Equal(`{"b": 1}`, string(payloads[0].Raw))
+	a.Equal(`[1, 2]`, string(payloads[1].Raw))
+	// Lots of unclosed brackets are read in one pass
+	text := bytes.Repeat([]byte(`{"a": [`), 200000)
+	a.Len((&Segment{Text: text}).Payloads(), 0)
+}
+
+func TestDecodeSkipsInvalid(t *testing.T) {
+	a := assert.New(t)
+	s := &Segment{Text: []byte(`[Rank] {not json} {"playerId": "abc"}`)}
+	update, err := s.ParseRankUpdated()
+	a.Nil(err)
+	a.Equal("abc", update.PlayerID)
+
+	s = &Segment{Text: []byte(`{not json}`)}
+	_, err = s.ParseRankUpdated()
+	perr, ok := err.(*PayloadError)
+	a.True(ok)
+	a.Equal(0, perr.Offset)
+}
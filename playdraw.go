@@ -0,0 +1,70 @@
+package gathering
+
+// The GRE messages about who goes first
+const (
+	GREConnectResp          = "GREMessageType_ConnectResp"
+	GREDieRollResultsResp   = "GREMessageType_DieRollResultsResp"
+	GREChooseStartingPlayer = "GREMessageType_ChooseStartingPlayerReq"
+)
+
+// DieRollResultsResp has the die rolls deciding who chooses to play or draw
+// in the first game
+type DieRollResultsResp struct {
+	PlayerDieRolls []PlayerDieRoll `json:"playerDieRolls"`
+}
+
+// PlayerDieRoll is what a seat rolled
+type PlayerDieRoll struct {
+	SystemSeatID int `json:"systemSeatId"`
+	RollValue    int `json:"rollValue"`
+}
+
+// recordStart picks up the player's seat and the die rolls from GRE messages
+func (g *ArenaGame) recordStart(msg *GreToClientMessages) {
+	switch msg.Type {
+	case GREConnectResp, GREMulliganReq, GREChooseStartingPlayer:
+		// Only sent to the player
+		if len(msg.SystemSeatIDs) == 1 && g.seat == 0 {
+			g.seat = msg.SystemSeatIDs[0]
+		}
+	case GREDieRollResultsResp:
+		if msg.DieRollResultsResp == nil {
+			return
+		}
+		g.DieRolls = make(map[int]int)
+		for _, r := range msg.DieRollResultsResp.PlayerDieRolls {
+			g.DieRolls[r.SystemSeatID] = r.RollValue
+		}
+	}
+	g.updateOnThePlay()
+}
+
+// recordStartingSeat takes the active player of the first turn as the one who
+// went first. This works for every game of a match, whether it was decided by
+// the die roll or chosen by the loser of the last game.
+func (g *ArenaGame) recordStartingSeat(s *GameSnapshot) {
+	if g.StartingSeatID != nil || s.TurnInfo == nil || s.Turn() != 1 || s.TurnInfo.ActivePlayer == 0 {
+		return
+	}
+	seat := s.TurnInfo.ActivePlayer
+	g.StartingSeatID = &seat
+	g.updateOnThePlay()
+}
+
+// updateOnThePlay works out if the player went first once we know their seat
+// and the starting seat. GameStop has the starting team, which is used when
+// the first turn wasn't in the log.
+func (g *ArenaGame) updateOnThePlay() {
+	var onThePlay bool
+	switch {
+	case g.StartingSeatID != nil && g.SeatID != nil:
+		onThePlay = *g.StartingSeatID == *g.SeatID
+	case g.StartingSeatID != nil && g.seat != 0:
+		onThePlay = *g.StartingSeatID == g.seat
+	case g.StartingTeamID != nil && g.TeamID != nil:
+		onThePlay = *g.StartingTeamID == *g.TeamID
+	default:
+		return
+	}
+	g.OnThePlay = &onThePlay
+}
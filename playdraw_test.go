@@ -0,0 +1,69 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDieRollEvent = `{
+ "greToClientEvent": {"greToClientMessages": [
+  {"type": "GREMessageType_ConnectResp", "systemSeatIds": [1]},
+  {"type": "GREMessageType_DieRollResultsResp", "systemSeatIds": [1, 2],
+   "dieRollResultsResp": {"playerDieRolls": [{"systemSeatId": 1, "rollValue": 4}, {"systemSeatId": 2, "rollValue": 17}]}},
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Full", "gameStateId": 1,
+   "turnInfo": {"turnNumber": 1, "activePlayer": 2}}},
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Diff", "gameStateId": 2, "prevGameStateId": 1,
+   "turnInfo": {"turnNumber": 2, "activePlayer": 1}}}
+ ]}
+}`
+
+func TestGameDieRoll(t *testing.T) {
+	a := assert.New(t)
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(testDieRollEvent), &event))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&event)
+	g := match.Games[0]
+	a.Equal(map[int]int{1: 4, 2: 17}, g.DieRolls)
+	a.Equal(2, *g.StartingSeatID)
+	a.False(*g.OnThePlay)
+}
+
+func TestGameOnThePlayAfterSideboarding(t *testing.T) {
+	a := assert.New(t)
+	// Game two has no die roll, the loser of game one chose to play
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&ArenaMatchEvent{GreToClientEvent: GreToClientEvent{
+		GreToClientMessages: []GreToClientMessages{{
+			Type: GREGameStateMessage,
+			GameStateMessage: GameStateMessage{
+				Type:     GameStateFull,
+				TurnInfo: &TurnInfo{TurnNumber: 1, ActivePlayer: 1},
+			},
+		}},
+	}})
+	g := match.Games[0]
+	a.Equal(1, *g.StartingSeatID)
+	// We don't know our seat until the game ends
+	a.Nil(g.OnThePlay)
+	seat := 1
+	match.UpdateGameEnd(&ArenaGame{SeatID: &seat, TeamID: &seat})
+	a.True(*g.OnThePlay)
+	a.Nil(g.DieRolls)
+}
+
+func TestGameOnThePlayFromGameStop(t *testing.T) {
+	a := assert.New(t)
+	var end ArenaGame
+	a.Nil(json.Unmarshal([]byte(`{"seatId": 1, "teamId": 1, "startingTeamId": 2}`), &end))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.UpdateGameEnd(&end)
+	g := match.Games[0]
+	a.Equal(2, *g.StartingTeamID)
+	a.Nil(g.StartingSeatID)
+	a.False(*g.OnThePlay)
+}
@@ -1,9 +1,5 @@
 package gathering
 
-import (
-	"encoding/json"
-)
-
 // ArenaRankInfo contains a players rank info
 type ArenaRankInfo struct {
 	PlayerID                 *string `json:"playerId"`
@@ -53,24 +49,24 @@ type RankUpdated struct {
 
 // IsRankInfo checks if a segment contains Rank Info
 func (s *Segment) IsRankInfo() bool {
-	return s.SegmentType == EventGetCombinedRankInfo
+	return s.Is(EventGetCombinedRankInfo)
 }
 
 // IsRankUpdated checks if a segment contains Rank Update
 func (s *Segment) IsRankUpdated() bool {
-	return s.SegmentType == InventoryRankUpdated
+	return s.Is(InventoryRankUpdated)
 }
 
 // ParseRankInfo parses the rank information out of a segment.
 func (s *Segment) ParseRankInfo() (*ArenaRankInfo, error) {
 	var rank ArenaRankInfo
-	err := json.Unmarshal(stripNonJSON(s.Text), &rank)
+	err := s.decodeObject(&rank)
 	return &rank, err
 }
 
 // ParseRankUpdated parses the rank update
 func (s *Segment) ParseRankUpdated() (*RankUpdated, error) {
 	var update RankUpdated
-	err := json.Unmarshal(stripNonJSON(s.Text), &update)
+	err := s.decodeObject(&update)
 	return &update, err
 }
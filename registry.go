@@ -0,0 +1,135 @@
+package gathering
+
+import (
+	"fmt"
+	"sync"
+)
+
+// firstCustomSegmentType is where registered segment types are numbered from,
+// well clear of the built in types so adding one doesn't renumber them.
+const firstCustomSegmentType SegmentType = 1000
+
+// Matcher reports whether a segment's text contains a kind of message.
+// *regexp.Regexp is a Matcher.
+type Matcher interface {
+	Match(b []byte) bool
+}
+
+// Decoder turns a segment into a value, for example by calling
+// Segment.JSON with a struct of your own.
+type Decoder func(s *Segment) (interface{}, error)
+
+// DecodeError is recorded in Log.Errors when a registered Decoder fails
+type DecodeError struct {
+	SegmentType SegmentType
+	Range       []int
+	Err         error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding %v at lines %v: %v", e.SegmentType, e.Range, e.Err)
+}
+
+var registry = struct {
+	sync.RWMutex
+	next     SegmentType
+	decoders map[SegmentType]Decoder
+}{
+	next:     firstCustomSegmentType,
+	decoders: make(map[SegmentType]Decoder),
+}
+
+// RegisterSegmentType adds a type of segment the library doesn't know about.
+// Segments matching it are tagged with the returned SegmentType while parsing,
+// and if a decoder is given, it is run and the result is available from
+// Segment.Value and Log.Values.
+// Registered types are checked after the built in types, so they only become
+// a segment's primary SegmentType when nothing else matched. Registering a name
+// twice panics, as does a nil matcher.
+func RegisterSegmentType(name string, matcher Matcher, decoder Decoder) SegmentType {
+	if matcher == nil {
+		panic("gathering: RegisterSegmentType matcher is nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	for _, n := range segmentTypeNames {
+		if n == name {
+			panic("gathering: RegisterSegmentType called twice for " + name)
+		}
+	}
+	t := registry.next
+	registry.next++
+	segmentTypeNames[t] = name
+	segmentRules = append(segmentRules, segmentRule{
+		Type:  t,
+		Match: matcher,
+	})
+	if decoder != nil {
+		registry.decoders[t] = decoder
+	}
+	return t
+}
+
+// LookupSegmentType finds a segment type by name, built in or registered
+func LookupSegmentType(name string) (SegmentType, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for t, n := range segmentTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return Unknown, false
+}
+
+// Value returns what the registered decoder for t produced for this segment
+func (s *Segment) Value(t SegmentType) (interface{}, bool) {
+	v, ok := s.values[t]
+	return v, ok
+}
+
+// Values returns everything decoded for a registered segment type, in the
+// order it appears in the log
+func (l *Log) Values(t SegmentType) []interface{} {
+	var values []interface{}
+	for _, s := range l.Segments {
+		if v, ok := s.values[t]; ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// decodeRegistered runs the registered decoders for the segment's types.
+// The decoders are run without holding the registry lock, they may look up
+// segment types themselves.
+func decodeRegistered(s *Segment) []error {
+	registry.RLock()
+	var types []SegmentType
+	var decoders []Decoder
+	for _, t := range s.Types {
+		if decoder, ok := registry.decoders[t]; ok {
+			types = append(types, t)
+			decoders = append(decoders, decoder)
+		}
+	}
+	registry.RUnlock()
+	var errs []error
+	for i, decoder := range decoders {
+		t := types[i]
+		v, err := decoder(s)
+		if err != nil {
+			errs = append(errs, &DecodeError{
+				SegmentType: t,
+				Range:       s.Range,
+				Err:         err,
+			})
+			continue
+		}
+		if s.values == nil {
+			s.values = make(map[SegmentType]interface{})
+		}
+		s.values[t] = v
+	}
+	return errs
+}
@@ -0,0 +1,139 @@
+package gathering
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type questTrackDetail struct {
+	CompletedQuestDetails []struct {
+		LocName    string
+		ChainIndex int
+	}
+}
+
+var questTrackDetailType = RegisterSegmentType(
+	"QuestGetTrackDetail",
+	regexp.MustCompile(`<==\sQuest\.GetTrackDetail\(\d+\)`),
+	func(s *Segment) (interface{}, error) {
+		var detail questTrackDetail
+		err := s.JSON(&detail)
+		return &detail, err
+	},
+)
+
+// registerForTest registers a segment type until the end of the test, so it
+// doesn't change what other tests parse
+func registerForTest(t *testing.T, name string, matcher Matcher, decoder Decoder) SegmentType {
+	st := RegisterSegmentType(name, matcher, decoder)
+	t.Cleanup(func() {
+		registry.Lock()
+		defer registry.Unlock()
+		delete(segmentTypeNames, st)
+		delete(registry.decoders, st)
+		for i, r := range segmentRules {
+			if r.Type == st {
+				segmentRules = append(segmentRules[:i:i], segmentRules[i+1:]...)
+				break
+			}
+		}
+	})
+	return st
+}
+
+func TestRegisterSegmentType(t *testing.T) {
+	a := assert.New(t)
+	f, err := os.Open("test/march-constructed.txt")
+	a.Nil(err)
+	alog, err := ParseLog(f)
+	a.Nil(err)
+	values := alog.Values(questTrackDetailType)
+	a.Len(values, 1)
+	detail := values[0].(*questTrackDetail)
+	a.Equal("OB-NPE-C-14", detail.CompletedQuestDetails[0].LocName)
+	a.Equal(14, detail.CompletedQuestDetails[0].ChainIndex)
+	found := false
+	for _, s := range alog.Segments {
+		if s.Is(questTrackDetailType) {
+			found = true
+			a.Equal(questTrackDetailType, s.SegmentType)
+			_, ok := s.Value(questTrackDetailType)
+			a.True(ok)
+		}
+	}
+	a.True(found)
+	a.Len(alog.Errors, 0)
+}
+
+// testQuestsLog parses a log with a single Quest.GetPlayerQuests response
+func testQuestsLog(t *testing.T) *Log {
+	p := NewLogParser(nil)
+	_, err := p.Parse(bytes.NewBufferString("[UnityCrossThreadLogger]4/2/2019 3:01:51 PM\n<== Quest.GetPlayerQuests(1)\n{}\n"))
+	assert.Nil(t, err)
+	p.Flush()
+	return p.Log()
+}
+
+func TestRegisterSegmentTypeDecodeError(t *testing.T) {
+	a := assert.New(t)
+	failing := registerForTest(t, "AlwaysFails", regexp.MustCompile(`<==\sQuest\.GetPlayerQuests\(\d+\)`),
+		func(s *Segment) (interface{}, error) {
+			return nil, errors.New("nope")
+		})
+	alog := testQuestsLog(t)
+	a.Len(alog.Errors, 1)
+	decodeErr, ok := alog.Errors[0].(*DecodeError)
+	a.True(ok)
+	a.Equal(failing, decodeErr.SegmentType)
+	_, ok = alog.Segments[1].Value(failing)
+	a.False(ok)
+}
+
+func TestRegisteredDecoderUsesRegistry(t *testing.T) {
+	a := assert.New(t)
+	// A decoder that waits on someone registering a type must not hold the
+	// registry while it runs
+	var name string
+	registerForTest(t, "RegistersMore", regexp.MustCompile(`<==\sQuest\.GetPlayerQuests\(\d+\)`),
+		func(s *Segment) (interface{}, error) {
+			done := make(chan SegmentType)
+			go func() {
+				done <- registerForTest(t, "RegisteredWhileDecoding", regexp.MustCompile(`never`), nil)
+			}()
+			select {
+			case st := <-done:
+				name = st.String()
+			case <-time.After(time.Second):
+				return nil, errors.New("registry is locked")
+			}
+			return name, nil
+		})
+	alog := testQuestsLog(t)
+	a.Len(alog.Errors, 0)
+	a.Equal("RegisteredWhileDecoding", name)
+}
+
+func TestLookupSegmentType(t *testing.T) {
+	a := assert.New(t)
+	found, ok := LookupSegmentType("QuestGetTrackDetail")
+	a.True(ok)
+	a.Equal(questTrackDetailType, found)
+	a.Equal("QuestGetTrackDetail", found.String())
+	found, ok = LookupSegmentType("MatchStart")
+	a.True(ok)
+	a.Equal(MatchStart, found)
+	_, ok = LookupSegmentType("Nothing")
+	a.False(ok)
+}
+
+func TestRegisterSegmentTypeTwice(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterSegmentType("QuestGetTrackDetail", regexp.MustCompile(`x`), nil)
+	})
+}
@@ -0,0 +1,43 @@
+package replay
+
+import (
+	"html/template"
+	"io"
+)
+
+// page is a self contained HTML page for a replay, with no scripts or
+// outside styles so it can be shared as a single file
+var page = template.Must(template.New("replay").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; color: #222; }
+h2 { font-size: 1.1em; margin: 1.5em 0 0.5em; border-bottom: 1px solid #ccc; }
+ol { list-style: none; padding: 0; margin: 0; }
+li { padding: 0.15em 0.5em; }
+li.you { border-left: 3px solid #2a7ae2; }
+li.opponent { border-left: 3px solid #d9534f; }
+li.other { border-left: 3px solid #ccc; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- $seat := .Seat}}
+{{- range .Turns}}
+<h2>Turn {{.Number}} ({{.Player}})</h2>
+<ol>
+{{- range .Entries}}
+<li class="{{if eq .Seat 0}}other{{else if eq .Seat $seat}}you{{else}}opponent{{end}}">{{.Text}}</li>
+{{- end}}
+</ol>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the play-by-play as an HTML page
+func (r *Replay) WriteHTML(w io.Writer) error {
+	return page.Execute(w, r)
+}
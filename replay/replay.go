@@ -0,0 +1,292 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gathering-gg/parser"
+)
+
+// Entry is one line of the play-by-play. Seat is the player it is about, or
+// 0 when it isn't about either.
+type Entry struct {
+	Turn        int    `json:"turn"`
+	GameStateID int    `json:"gameStateId"`
+	Seat        int    `json:"seat"`
+	Text        string `json:"text"`
+
+	order int
+}
+
+// Turn is the entries of one turn of the game
+type Turn struct {
+	Number       int     `json:"number"`
+	ActivePlayer int     `json:"activePlayer"`
+	Player       string  `json:"player"`
+	Entries      []Entry `json:"entries"`
+}
+
+// Replay is a game written out as a play-by-play, from the player's side.
+// The player is "You", the opponent is "Opponent".
+type Replay struct {
+	MatchID    string           `json:"matchId"`
+	GameNumber int              `json:"gameNumber"`
+	Opponent   string           `json:"opponent"`
+	Result     gathering.Result `json:"result"`
+	Reason     string           `json:"reason"`
+	OnThePlay  *bool            `json:"onThePlay"`
+	Seat       int              `json:"seat"`
+	Turns      []Turn           `json:"turns"`
+}
+
+// builder has what is needed to put a game into words
+type builder struct {
+	game  *gathering.ArenaGame
+	cards gathering.CardDatabase
+	seat  int
+}
+
+// New writes out a game of the match. Cards are named from the card
+// database, which may be nil; unknown cards are shown by grpId.
+func New(match *gathering.ArenaMatch, game *gathering.ArenaGame, cards gathering.CardDatabase) *Replay {
+	b := &builder{game: game, cards: cards, seat: game.PlayerSeat()}
+	r := &Replay{
+		MatchID:   match.MatchID,
+		Opponent:  match.OpponentScreenName,
+		Result:    game.Result,
+		OnThePlay: game.OnThePlay,
+		Seat:      b.seat,
+	}
+	if game.Number != nil {
+		r.GameNumber = *game.Number
+	}
+	if game.WinningReason != nil {
+		r.Reason = strings.TrimPrefix(*game.WinningReason, "ResultReason_")
+	}
+	turns := make(map[int]*Turn)
+	for _, t := range game.Turns {
+		r.Turns = append(r.Turns, Turn{
+			Number:       t.Number,
+			ActivePlayer: t.ActivePlayer,
+			Player:       b.player(t.ActivePlayer),
+		})
+	}
+	for i := range r.Turns {
+		turns[r.Turns[i].Number] = &r.Turns[i]
+	}
+	add := func(e Entry) {
+		if t, ok := turns[e.Turn]; ok && e.Text != "" {
+			t.Entries = append(t.Entries, e)
+		}
+	}
+	for _, t := range game.Turns {
+		for _, e := range t.Events {
+			add(b.event(t.Number, e))
+		}
+	}
+	for _, a := range game.Actions {
+		add(b.action(a))
+	}
+	for i := range r.Turns {
+		entries := r.Turns[i].Entries
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].order < entries[j].order
+		})
+	}
+	return r
+}
+
+// player is what a seat is called
+func (b *builder) player(seat int) string {
+	if seat == b.seat {
+		return "You"
+	}
+	return "Opponent"
+}
+
+// says is the player in a seat doing something, like "You cast" or
+// "Opponent casts"
+func (b *builder) says(seat int, you, they string) string {
+	if seat == b.seat {
+		return "You " + you
+	}
+	return "Opponent " + they
+}
+
+// card is the name of a card, or its grpId if the database doesn't know it
+func (b *builder) card(grpID int) string {
+	if grpID == 0 {
+		return "a card"
+	}
+	if b.cards != nil {
+		if c, ok := b.cards.Card(grpID); ok && c.Name != "" {
+			return c.Name
+		}
+	}
+	return fmt.Sprintf("#%v", grpID)
+}
+
+// instance is the name of the card with an instance id. The lineage knows
+// cards the event didn't, like ones revealed later.
+func (b *builder) instance(instanceID, grpID int) string {
+	if grpID == 0 {
+		if c := b.game.Lineage().Card(instanceID); c != nil {
+			grpID = c.GrpID
+		}
+	}
+	return b.card(grpID)
+}
+
+// owner is the seat that owns an instance, from the card or the zones it
+// moved between
+func (b *builder) owner(instanceID int, state *gathering.GameSnapshot, zones ...int) int {
+	if c := b.game.Lineage().Card(instanceID); c != nil && c.OwnerSeatID != 0 {
+		return c.OwnerSeatID
+	}
+	if state == nil {
+		return 0
+	}
+	if o, ok := state.Objects[instanceID]; ok && o.OwnerSeatID != 0 {
+		return o.OwnerSeatID
+	}
+	for _, id := range zones {
+		if z, ok := state.Zones[id]; ok && z.OwnerSeatID != 0 {
+			return z.OwnerSeatID
+		}
+	}
+	return 0
+}
+
+// zoneName is a zone type without its prefix, like "Graveyard"
+func zoneName(zoneType string) string {
+	return strings.ToLower(strings.TrimPrefix(zoneType, "ZoneType_"))
+}
+
+// event puts a game event into words. Events that aren't worth a line get
+// no text.
+func (b *builder) event(turn int, e *gathering.GameEvent) Entry {
+	state := b.game.State().At(e.GameStateID)
+	entry := Entry{Turn: turn, GameStateID: e.GameStateID, order: e.GameStateID * 2}
+	switch {
+	case e.ZoneTransfer != nil:
+		t := e.ZoneTransfer
+		entry.Seat = b.owner(t.InstanceID, state, t.ZoneSrc, t.ZoneDest)
+		card := b.instance(t.InstanceID, t.GrpID)
+		switch {
+		case t.Category == "CastSpell":
+			entry.Text = b.says(entry.Seat, "cast ", "casts ") + card
+		case t.Category == "PlayLand":
+			entry.Text = b.says(entry.Seat, "play ", "plays ") + card
+		case t.Category == "Draw" && entry.Seat == b.seat:
+			entry.Text = "You draw " + b.card(t.GrpID)
+		case t.Category == "Draw":
+			entry.Text = "Opponent draws a card"
+		case t.Category == "Discard":
+			entry.Text = b.says(entry.Seat, "discard ", "discards ") + card
+		case t.Category == "Sacrifice":
+			entry.Text = b.says(entry.Seat, "sacrifice ", "sacrifices ") + card
+		case t.Category == "Countered":
+			entry.Text = card + " is countered"
+		case t.ZoneSrcType == "ZoneType_Battlefield" && t.ZoneDestType == "ZoneType_Graveyard":
+			entry.Text = card + " dies"
+		case t.ZoneDestType == "ZoneType_Exile":
+			entry.Text = card + " is exiled"
+		case t.Category == "Resolve", t.ZoneDestType == "ZoneType_Limbo":
+			// A spell resolving is told by what it does
+		case t.ZoneSrcType == "ZoneType_Battlefield" || t.ZoneDestType == "ZoneType_Battlefield":
+			entry.Text = fmt.Sprintf("%v moves from the %v to the %v", card, zoneName(t.ZoneSrcType), zoneName(t.ZoneDestType))
+		}
+	case e.DamageDealt != nil:
+		d := e.DamageDealt
+		entry.Seat = b.owner(d.SourceID, state)
+		target := b.instance(d.TargetID, d.TargetGrpID)
+		if d.TargetSeatID == b.seat {
+			target = "you"
+		} else if d.TargetSeatID != 0 {
+			target = b.player(d.TargetSeatID)
+		}
+		entry.Text = fmt.Sprintf("%v deals %v damage to %v", b.instance(d.SourceID, d.SourceGrpID), d.Damage, target)
+	case e.ModifiedLife != nil:
+		l := e.ModifiedLife
+		entry.Seat = l.SeatID
+		if state != nil {
+			entry.Text = fmt.Sprintf("%v %v", b.says(l.SeatID, "go to", "goes to"), state.Life(l.SeatID))
+		} else if l.Change < 0 {
+			entry.Text = fmt.Sprintf("%v %v life", b.says(l.SeatID, "lose", "loses"), -l.Change)
+		} else {
+			entry.Text = fmt.Sprintf("%v %v life", b.says(l.SeatID, "gain", "gains"), l.Change)
+		}
+	}
+	return entry
+}
+
+// action puts what the player did into words. Casting and playing cards is
+// left to the events, which have both players.
+func (b *builder) action(a *gathering.ArenaAction) Entry {
+	entry := Entry{Turn: a.Turn, GameStateID: a.GameStateID, Seat: b.seat, order: a.GameStateID*2 + 1}
+	var targets []string
+	for _, t := range a.Targets {
+		targets = append(targets, b.instance(t.InstanceID, t.GrpID))
+	}
+	switch a.Kind {
+	case gathering.ActionAttack:
+		entry.Text = "You attack with " + b.instance(a.Card.InstanceID, a.Card.GrpID)
+	case gathering.ActionBlock:
+		entry.Text = "You block"
+		if len(targets) > 0 {
+			entry.Text += " " + strings.Join(targets, " and ")
+		}
+		entry.Text += " with " + b.instance(a.Card.InstanceID, a.Card.GrpID)
+	case gathering.ActionTarget:
+		if len(targets) > 0 {
+			entry.Text = "You target " + strings.Join(targets, " and ")
+		}
+	case gathering.ActionConcede:
+		entry.Text = "You concede"
+	}
+	return entry
+}
+
+// Title is a line about the game, like "Game 1 vs Bob: win, on the play"
+func (r *Replay) Title() string {
+	title := fmt.Sprintf("Game %v", r.GameNumber)
+	if r.Opponent != "" {
+		title += " vs " + r.Opponent
+	}
+	var about []string
+	if r.Result != "" {
+		about = append(about, string(r.Result))
+	}
+	if r.Reason != "" {
+		about = append(about, "by "+strings.ToLower(r.Reason))
+	}
+	if r.OnThePlay != nil && *r.OnThePlay {
+		about = append(about, "on the play")
+	} else if r.OnThePlay != nil {
+		about = append(about, "on the draw")
+	}
+	if len(about) > 0 {
+		title += ": " + strings.Join(about, ", ")
+	}
+	return title
+}
+
+// WriteText writes the play-by-play as plain text, one line per entry
+func (r *Replay) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, r.Title()); err != nil {
+		return err
+	}
+	for _, t := range r.Turns {
+		if _, err := fmt.Fprintf(w, "\nTurn %v (%v)\n", t.Number, t.Player); err != nil {
+			return err
+		}
+		for _, e := range t.Entries {
+			if _, err := fmt.Fprintf(w, "T%v %v\n", t.Number, e.Text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
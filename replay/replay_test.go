@@ -0,0 +1,102 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gathering-gg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const testReplayEvent = `{"greToClientEvent": {"greToClientMessages": [
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+  "type": "GameStateType_Full", "gameStateId": 1,
+  "turnInfo": {"turnNumber": 3, "activePlayer": 2},
+  "players": [{"lifeTotal": 20, "systemSeatNumber": 1}, {"lifeTotal": 20, "systemSeatNumber": 2}],
+  "zones": [
+   {"zoneId": 27, "type": "ZoneType_Stack"},
+   {"zoneId": 28, "type": "ZoneType_Battlefield", "objectInstanceIds": [170, 180]},
+   {"zoneId": 35, "type": "ZoneType_Hand", "ownerSeatId": 2, "objectInstanceIds": [190]}
+  ],
+  "gameObjects": [
+   {"instanceId": 170, "grpId": 68800, "type": "GameObjectType_Card", "zoneId": 28, "ownerSeatId": 1},
+   {"instanceId": 180, "grpId": 68900, "type": "GameObjectType_Card", "zoneId": 28, "ownerSeatId": 2}
+  ]}},
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+  "type": "GameStateType_Diff", "gameStateId": 2, "prevGameStateId": 1,
+  "zones": [
+   {"zoneId": 27, "type": "ZoneType_Stack", "objectInstanceIds": [191]},
+   {"zoneId": 35, "type": "ZoneType_Hand", "ownerSeatId": 2}
+  ],
+  "gameObjects": [{"instanceId": 191, "grpId": 68739, "type": "GameObjectType_Card", "zoneId": 27, "ownerSeatId": 2}],
+  "annotations": [
+   {"id": 1, "affectedIds": [190], "type": ["AnnotationType_ObjectIdChanged"], "details": [
+    {"key": "orig_id", "valueInt32": [190]}, {"key": "new_id", "valueInt32": [191]}]},
+   {"id": 2, "affectedIds": [191], "type": ["AnnotationType_ZoneTransfer"], "details": [
+    {"key": "zone_src", "valueInt32": [35]}, {"key": "zone_dest", "valueInt32": [27]},
+    {"key": "category", "valueString": ["CastSpell"]}]}
+  ]}},
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+  "type": "GameStateType_Diff", "gameStateId": 3, "prevGameStateId": 2,
+  "players": [{"lifeTotal": 14, "systemSeatNumber": 1}],
+  "annotations": [
+   {"id": 3, "affectorId": 180, "affectedIds": [1], "type": ["AnnotationType_DamageDealt"], "details": [
+    {"key": "damage", "valueInt32": [6]}]},
+   {"id": 4, "affectorId": 180, "affectedIds": [1], "type": ["AnnotationType_ModifiedLife"], "details": [
+    {"key": "life", "valueInt32": [-6]}]}
+  ]}}
+]}}`
+
+func testGame(t *testing.T) (*gathering.ArenaMatch, *gathering.ArenaGame) {
+	var event gathering.ArenaMatchEvent
+	assert.Nil(t, json.Unmarshal([]byte(testReplayEvent), &event))
+	number, seat := 1, 1
+	onThePlay := true
+	game := &gathering.ArenaGame{Number: &number, SeatID: &seat, OnThePlay: &onThePlay, Result: gathering.ResultLoss}
+	match := &gathering.ArenaMatch{MatchID: "m1", OpponentScreenName: "Bob", Games: []*gathering.ArenaGame{game}}
+	match.LogMatchEvent(&event)
+	s := &gathering.Segment{Text: []byte(`{"requestId": 1,
+ "clientToMatchServiceMessageType": "ClientToMatchServiceMessageType_ClientToGREMessage",
+ "payload": {"type": "ClientMessageType_DeclareBlockersResp", "gameStateId": 2, "declareBlockersResp": {
+  "selectedBlockers": [{"blockerInstanceId": 170, "selectedAttackerInstanceIds": [180]}]}}}`)}
+	msg, err := s.ParseClientToGRE()
+	assert.Nil(t, err)
+	match.LogClientMessage(msg, nil)
+	return match, game
+}
+
+func TestReplayText(t *testing.T) {
+	a := assert.New(t)
+	match, game := testGame(t)
+	cards := gathering.CardMap{
+		68739: {GrpID: 68739, Name: "Shock"},
+		68800: {GrpID: 68800, Name: "Llanowar Elves"},
+	}
+	r := New(match, game, cards)
+	a.Len(r.Turns, 1)
+	a.Equal("Opponent", r.Turns[0].Player)
+	var b bytes.Buffer
+	a.Nil(r.WriteText(&b))
+	a.Equal(`Game 1 vs Bob: loss, on the play
+
+Turn 3 (Opponent)
+T3 Opponent casts Shock
+T3 You block #68900 with Llanowar Elves
+T3 #68900 deals 6 damage to you
+T3 You go to 14
+`, b.String())
+}
+
+func TestReplayHTML(t *testing.T) {
+	a := assert.New(t)
+	match, game := testGame(t)
+	match.OpponentScreenName = "<b>Bob</b>"
+	r := New(match, game, nil)
+	var b bytes.Buffer
+	a.Nil(r.WriteHTML(&b))
+	html := b.String()
+	a.Contains(html, "<title>Game 1 vs &lt;b&gt;Bob&lt;/b&gt;: loss, on the play</title>")
+	a.Contains(html, `<li class="opponent">Opponent casts #68739</li>`)
+	a.Contains(html, `<li class="you">You go to 14</li>`)
+}
@@ -0,0 +1,135 @@
+package gathering
+
+import (
+	"fmt"
+)
+
+// Result is the outcome of a game or match for the player. It is empty when
+// we don't know it.
+type Result string
+
+// The results of a game or match
+const (
+	ResultWin  Result = "win"
+	ResultLoss Result = "loss"
+	ResultDraw Result = "draw"
+)
+
+// The scopes and types of the results in MatchFinalMatchResult.ResultList
+const (
+	matchScopeGame  = "MatchScope_Game"
+	matchScopeMatch = "MatchScope_Match"
+	resultTypeDraw  = "ResultType_Draw"
+)
+
+// resultFor is the result for team when winner won. A winner of 0 is a draw.
+// Concessions and timeouts are wins and losses like any other.
+func resultFor(team, winner int) Result {
+	switch {
+	case winner == 0:
+		return ResultDraw
+	case team == 0:
+		return ""
+	case winner == team:
+		return ResultWin
+	}
+	return ResultLoss
+}
+
+// updateResult sets the game result once it has ended. team is the player's
+// team.
+func (g *ArenaGame) updateResult(team int) {
+	if g.WinningTeamID == nil || team == 0 {
+		return
+	}
+	g.Result = resultFor(team, *g.WinningTeamID)
+	g.updateLostToTimeout()
+}
+
+// updateLostToTimeout flags a game the player lost on time
+func (g *ArenaGame) updateLostToTimeout() {
+	g.LostToTimeout = g.Result == ResultLoss && g.WinningReason != nil && *g.WinningReason == resultReasonTimeout
+}
+
+// teamID is the player's team, from whichever game knows it. Games without a
+// GameStop have it in the game state, with the player's seat.
+func (a *ArenaMatch) teamID() int {
+	for _, g := range a.Games {
+		if g.TeamID != nil {
+			return *g.TeamID
+		}
+	}
+	for _, g := range a.Games {
+		seat := g.PlayerSeat()
+		if seat == 0 || g.state == nil {
+			continue
+		}
+		if p, ok := g.state.Current().Players[seat]; ok && p.TeamID != 0 {
+			return p.TeamID
+		}
+	}
+	return 0
+}
+
+// updateScore counts the games won and lost
+func (a *ArenaMatch) updateScore() {
+	a.Wins, a.Losses = 0, 0
+	for _, g := range a.Games {
+		switch g.Result {
+		case ResultWin:
+			a.Wins++
+		case ResultLoss:
+			a.Losses++
+		}
+	}
+}
+
+// Score is the games won and lost, like 2-1
+func (a *ArenaMatch) Score() string {
+	return fmt.Sprintf("%d-%d", a.Wins, a.Losses)
+}
+
+// updateResults sets the match result, and the result of any game we didn't
+// see the end of, from the final results. Games conceded during sideboarding
+// only show up here. Older logs without a match result get one from the score.
+func (a *ArenaMatch) updateResults(final *MatchFinalMatchResult) {
+	a.MatchCompletedReason = final.MatchCompletedReason
+	team := a.teamID()
+	game := 0
+	for _, r := range final.ResultList {
+		winner := r.WinningTeamID
+		if r.Result == resultTypeDraw {
+			winner = 0
+		}
+		switch r.Scope {
+		case matchScopeGame:
+			// Games conceded while sideboarding may not have been started
+			if g := a.game(game + 1); g.Result == "" {
+				if g.WinningTeamID == nil {
+					g.WinningTeamID = &winner
+				}
+				if g.WinningReason == nil && r.Reason != "" {
+					reason := r.Reason
+					g.WinningReason = &reason
+				}
+				g.Result = resultFor(team, winner)
+				g.updateLostToTimeout()
+			}
+			game++
+		case matchScopeMatch:
+			a.Result = resultFor(team, winner)
+		}
+	}
+	a.updateScore()
+	if a.Result != "" {
+		return
+	}
+	switch {
+	case a.Wins > a.Losses:
+		a.Result = ResultWin
+	case a.Wins < a.Losses:
+		a.Result = ResultLoss
+	case a.Wins > 0:
+		a.Result = ResultDraw
+	}
+}
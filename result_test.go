@@ -0,0 +1,130 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGameEnd(team, winner int, reason string) *ArenaGame {
+	return &ArenaGame{
+		SeatID:        &team,
+		TeamID:        &team,
+		WinningTeamID: &winner,
+		WinningReason: &reason,
+	}
+}
+
+func testMatchCompleted(t *testing.T, final string) *ArenaMatchCompleted {
+	var done ArenaMatchCompleted
+	assert.Nil(t, json.Unmarshal([]byte(`{"matchGameRoomStateChangedEvent": {"gameRoomInfo": {"finalMatchResult": `+final+`}}}`), &done))
+	return &done
+}
+
+func TestMatchResultBestOfThree(t *testing.T) {
+	a := assert.New(t)
+	match := &ArenaMatch{Games: []*ArenaGame{{}, {}, {}}}
+	match.UpdateGameEnd(testGameEnd(1, 2, "ResultReason_Game"))
+	match.currentGame++
+	match.UpdateGameEnd(testGameEnd(1, 1, "ResultReason_Concede"))
+	match.currentGame++
+	match.UpdateGameEnd(testGameEnd(1, 1, "ResultReason_Timeout"))
+	a.Equal(ResultLoss, match.Games[0].Result)
+	a.Equal(ResultWin, match.Games[1].Result)
+	a.Equal(ResultWin, match.Games[2].Result)
+	a.Equal("2-1", match.Score())
+	match.UpdateMatchCompleted(testMatchCompleted(t, `{
+		"matchCompletedReason": "MatchCompletedReasonType_Success",
+		"resultList": [
+			{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 2},
+			{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1},
+			{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1},
+			{"scope": "MatchScope_Match", "result": "ResultType_WinLoss", "winningTeamId": 1}
+		]}`))
+	a.Equal(ResultWin, match.Result)
+	a.Equal("MatchCompletedReasonType_Success", match.MatchCompletedReason)
+	a.Equal(2, match.Wins)
+	a.Equal(1, match.Losses)
+}
+
+func TestMatchResultConcededBetweenGames(t *testing.T) {
+	a := assert.New(t)
+	// Game two was conceded while sideboarding, so it never had a GameStop or
+	// even started
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.UpdateGameEnd(testGameEnd(2, 1, "ResultReason_Game"))
+	match.UpdateMatchCompleted(testMatchCompleted(t, `{
+		"matchCompletedReason": "MatchCompletedReasonType_Concede",
+		"resultList": [
+			{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1},
+			{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1},
+			{"scope": "MatchScope_Match", "result": "ResultType_WinLoss", "winningTeamId": 1}
+		]}`))
+	a.Len(match.Games, 2)
+	a.Equal(ResultLoss, match.Games[1].Result)
+	a.Equal(ResultLoss, match.Result)
+	a.Equal("0-2", match.Score())
+	a.Equal("MatchCompletedReasonType_Concede", match.MatchCompletedReason)
+}
+
+func TestMatchResultDraw(t *testing.T) {
+	a := assert.New(t)
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.UpdateGameEnd(testGameEnd(1, 0, "ResultReason_Draw"))
+	match.UpdateMatchCompleted(testMatchCompleted(t, `{
+		"resultList": [
+			{"scope": "MatchScope_Game", "result": "ResultType_Draw"},
+			{"scope": "MatchScope_Match", "result": "ResultType_Draw"}
+		]}`))
+	a.Equal(ResultDraw, match.Games[0].Result)
+	a.Equal(ResultDraw, match.Result)
+	a.Equal("0-0", match.Score())
+}
+
+func TestMatchResultFromScore(t *testing.T) {
+	a := assert.New(t)
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.UpdateGameEnd(testGameEnd(1, 1, "ResultReason_Game"))
+	match.UpdateMatchCompleted(testMatchCompleted(t, `{}`))
+	a.Equal(ResultWin, match.Result)
+}
+
+func TestMatchResultFromGameState(t *testing.T) {
+	a := assert.New(t)
+	// No GameStop, so the team comes from the player's seat in the game state,
+	// and the result from the GRE results
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(`{"greToClientEvent": {"greToClientMessages": [
+ {"type": "GREMessageType_ConnectResp", "systemSeatIds": [2]},
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {"type": "GameStateType_Full", "gameStateId": 1,
+  "players": [{"systemSeatNumber": 1, "teamId": 2}, {"systemSeatNumber": 2, "teamId": 1}],
+  "gameInfo": {"gameNumber": 1, "matchState": "MatchState_GameComplete", "results": [
+   {"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 2, "reason": "ResultReason_Timeout"}]}}}
+]}}`), &event))
+	match := &ArenaMatch{}
+	match.LogMatchEvent(&event)
+	g := match.Games[0]
+	a.Equal(ResultLoss, g.Result)
+	a.True(g.LostToTimeout)
+	a.Equal("0-1", match.Score())
+}
+
+func TestMatchResultTimeoutFromFinalResults(t *testing.T) {
+	a := assert.New(t)
+	// The second game only shows up in the final results
+	match := &ArenaMatch{Games: []*ArenaGame{{}, {}}}
+	match.UpdateGameEnd(testGameEnd(1, 1, "ResultReason_Game"))
+	match.UpdateMatchCompleted(testMatchCompleted(t, `{
+		"resultList": [
+			{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 1, "reason": "ResultReason_Game"},
+			{"scope": "MatchScope_Game", "result": "ResultType_WinLoss", "winningTeamId": 2, "reason": "ResultReason_Timeout"},
+			{"scope": "MatchScope_Match", "result": "ResultType_WinLoss", "winningTeamId": 2}
+		]}`))
+	g := match.Games[1]
+	a.Equal(ResultLoss, g.Result)
+	a.Equal(2, *g.WinningTeamID)
+	a.Equal("ResultReason_Timeout", *g.WinningReason)
+	a.True(g.LostToTimeout)
+	a.False(match.Games[0].LostToTimeout)
+}
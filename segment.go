@@ -1,9 +1,10 @@
 package gathering
 
 import (
-	"bytes"
 	"encoding/json"
+	"fmt"
 	"regexp"
+	"sort"
 	"time"
 )
 
@@ -42,64 +43,144 @@ const (
 	DuelSceneSideboardingStart
 	DuelSceneSideboardingStop
 	MatchCompleted
+	ClientToGRE
 )
 
-var segmentTypeChecks = map[SegmentType]*regexp.Regexp{
-	PlayerInventoryGetPlayerInventory: regexp.MustCompile(`<==\sPlayerInventory\.GetPlayerInventory\(\d*\)`),
-	PlayerInventoryGetPlayerCards:     regexp.MustCompile(`<==\sPlayerInventory\.GetPlayerCardsV3\(\d*\)`),
-	EventGetCombinedRankInfo:          regexp.MustCompile(`<==\sEvent\.GetCombinedRankInfo\(\d+\)`),
-	DeckGetDeckLists:                  regexp.MustCompile(`<==\sDeck\.GetDeckListsV3\(\d+\)`),
-	PlayerAuth:                        regexp.MustCompile(`"screenName":\s"(.*)"`),
-	EventGetPlayerCourse:              regexp.MustCompile(`<==\sEvent\.GetPlayerCourse\(\d+\)`),
-	MatchStart:                        regexp.MustCompile(`Incoming\sEvent\.MatchCreated`),
-	MatchEnd:                          regexp.MustCompile(`DuelScene\.GameStop`),
-	MatchEvent:                        regexp.MustCompile(`"GREMessageType_GameStateMessage"|GameStateType_Diff`),
-	EventDeckSubmit:                   regexp.MustCompile(`<==\sEvent\.DeckSubmitV3\(\d+\)`),
-	CrackBooster:                      regexp.MustCompile(`<==\sPlayerInventory\.CrackBoostersV3\(\d+\)`),
-	InventoryRankUpdated:              regexp.MustCompile(`Incoming\sRank\.Updated`),
-	EventClaimPrize:                   regexp.MustCompile(`<==\sEvent\.ClaimPrize\(\d+\)`),
-	IncomingInventoryUpdate:           regexp.MustCompile(`Incoming\sInventory\.Updated`),
-	DuelSceneSideboardingStart:        regexp.MustCompile(`DuelScene\.SideboardingStart`),
-	DuelSceneSideboardingStop:         regexp.MustCompile(`DuelScene\.SideboardingStop`),
-	MatchCompleted:                    regexp.MustCompile(`MatchGameRoomStateType_MatchCompleted`),
+var segmentTypeNames = map[SegmentType]string{
+	Unknown:                           "Unknown",
+	PlayerInventoryGetPlayerInventory: "PlayerInventoryGetPlayerInventory",
+	PlayerInventoryGetPlayerCards:     "PlayerInventoryGetPlayerCards",
+	DeckGetDeckLists:                  "DeckGetDeckLists",
+	EventGetCombinedRankInfo:          "EventGetCombinedRankInfo",
+	EventJoin:                         "EventJoin",
+	EventPayEntry:                     "EventPayEntry",
+	EventGetPlayerCourse:              "EventGetPlayerCourse",
+	EventDeckSubmit:                   "EventDeckSubmit",
+	EventMatchCreated:                 "EventMatchCreated",
+	PlayerAuth:                        "PlayerAuth",
+	MatchStart:                        "MatchStart",
+	MatchEnd:                          "MatchEnd",
+	MatchEvent:                        "MatchEvent",
+	CrackBooster:                      "CrackBooster",
+	InventoryRankUpdated:              "InventoryRankUpdated",
+	EventClaimPrize:                   "EventClaimPrize",
+	IncomingInventoryUpdate:           "IncomingInventoryUpdate",
+	DuelSceneSideboardingStart:        "DuelSceneSideboardingStart",
+	DuelSceneSideboardingStop:         "DuelSceneSideboardingStop",
+	MatchCompleted:                    "MatchCompleted",
+	ClientToGRE:                       "ClientToGRE",
 }
 
-var cleaners = []*regexp.Regexp{
-	regexp.MustCompile(`<<<<<<<<<<.*`),
-	regexp.MustCompile(`\[\w.*`),
-	regexp.MustCompile(`\dx[\d\w]+.*`),
-	regexp.MustCompile(`(?m)ZoneTransferUXEvent.*`),
-	regexp.MustCompile(`(?sm)BIError - GRE.Notification:.*`),
+func (t SegmentType) String() string {
+	registry.RLock()
+	defer registry.RUnlock()
+	if name, ok := segmentTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("SegmentType(%d)", int(t))
 }
-var clean = []byte(`$1.$2`)
 
-var trimLeft = func(r rune) bool {
-	return r != '{' && r != '['
+// segmentRule tags a segment with a SegmentType when its text matches.
+// Rules with a higher priority are checked first, and the first match becomes
+// the segment's primary SegmentType. Specific API responses win over the GRE
+// messages they may be embedded in, which win over loose matches like
+// screenName.
+type segmentRule struct {
+	Type     SegmentType
+	Priority int
+	Match    Matcher
 }
-var trimRight = func(r rune) bool {
-	return r != '}' && r != ']'
+
+var screenNameRegex = regexp.MustCompile(`"screenName":\s"(.*)"`)
+
+var segmentRules = sortRules([]segmentRule{
+	{PlayerInventoryGetPlayerInventory, 100, MethodResponse("PlayerInventory.GetPlayerInventory")},
+	{PlayerInventoryGetPlayerCards, 100, MethodResponse("PlayerInventory.GetPlayerCards")},
+	{EventGetCombinedRankInfo, 100, MethodResponse("Event.GetCombinedRankInfo")},
+	{DeckGetDeckLists, 100, MethodResponse("Deck.GetDeckLists")},
+	{EventGetPlayerCourse, 100, MethodResponse("Event.GetPlayerCourse")},
+	{EventDeckSubmit, 100, MethodResponse("Event.DeckSubmit")},
+	{CrackBooster, 100, MethodResponse("PlayerInventory.CrackBoosters")},
+	{EventClaimPrize, 100, MethodResponse("Event.ClaimPrize")},
+	{MatchStart, 80, regexp.MustCompile(`Incoming\sEvent\.MatchCreated`)},
+	{InventoryRankUpdated, 80, regexp.MustCompile(`Incoming\sRank\.Updated`)},
+	{IncomingInventoryUpdate, 80, regexp.MustCompile(`Incoming\sInventory\.Updated`)},
+	{MatchEnd, 60, regexp.MustCompile(`DuelScene\.GameStop`)},
+	{DuelSceneSideboardingStart, 60, regexp.MustCompile(`DuelScene\.SideboardingStart`)},
+	{DuelSceneSideboardingStop, 60, regexp.MustCompile(`DuelScene\.SideboardingStop`)},
+	{MatchCompleted, 40, regexp.MustCompile(`MatchGameRoomStateType_MatchCompleted`)},
+	{MatchEvent, 40, regexp.MustCompile(`"greToClientEvent"\s*:`)},
+	{ClientToGRE, 40, regexp.MustCompile(clientToGREMessage)},
+	{PlayerAuth, 10, screenNameRegex},
+})
+
+// sortRules orders rules by priority. Rules with the same priority keep the
+// order they were declared in.
+func sortRules(rules []segmentRule) []segmentRule {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+	return rules
 }
 
-// Segment is a piece of the log
+// segmentMatcher is a Matcher that needs more of the segment than its text
+type segmentMatcher interface {
+	matchSegment(s *Segment) bool
+}
+
+// classify returns every SegmentType the segment matches, most important
+// first. Registered matchers are run without holding the registry lock.
+func classify(s *Segment) []SegmentType {
+	registry.RLock()
+	rules := segmentRules
+	registry.RUnlock()
+	var types []SegmentType
+	for _, r := range rules {
+		matched := false
+		if m, ok := r.Match.(segmentMatcher); ok {
+			matched = m.matchSegment(s)
+		} else {
+			matched = r.Match.Match(s.Text)
+		}
+		if matched {
+			types = append(types, r.Type)
+		}
+	}
+	return types
+}
+
+// Segment is a piece of the log. SegmentType is the most important type the
+// segment matched, Types holds all of them. Method is set for API requests and
+// responses.
 type Segment struct {
 	LoggerType  LoggerType
 	Time        *time.Time
 	SegmentType SegmentType
+	Types       []SegmentType
+	Method      *ArenaMethod
 	Text        []byte
 	Range       []int
 	Line        []byte
+	values      map[SegmentType]interface{}
 }
 
-// JSON parses the text as JSON
-func (s *Segment) JSON(v interface{}) error {
-	return json.Unmarshal(s.Text, v)
+// Is checks if the segment was tagged with the given type
+func (s *Segment) Is(t SegmentType) bool {
+	if s.SegmentType == t {
+		return true
+	}
+	for _, st := range s.Types {
+		if st == t {
+			return true
+		}
+	}
+	return false
 }
 
-func stripNonJSON(b []byte) []byte {
-	for _, c := range cleaners {
-		b = c.ReplaceAll(b, clean)
+// JSON parses the first JSON value in the text
+func (s *Segment) JSON(v interface{}) error {
+	if len(extractJSON(s.Text)) == 0 {
+		return json.Unmarshal(s.Text, v)
 	}
-	b = bytes.TrimLeftFunc(b, trimLeft)
-	b = bytes.TrimRightFunc(b, trimRight)
-	return b
+	return s.decode(0, v)
 }
@@ -0,0 +1,50 @@
+package gathering
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTypeMultipleMatches(t *testing.T) {
+	a := assert.New(t)
+	text := []byte(`{
+  "greToClientEvent": {
+    "greToClientMessages": [
+      {
+        "type": "GREMessageType_GameStateMessage",
+        "gameStateMessage": {
+          "type": "GameStateType_Diff",
+          "players": [{"screenName": "Abattoir#66546"}]
+        }
+      }
+    ]
+  }
+}`)
+	// Map iteration used to make this random, it must always be the same
+	for i := 0; i < 100; i++ {
+		primary, types := parseType(text)
+		a.Equal(MatchEvent, primary)
+		a.Equal([]SegmentType{MatchEvent, PlayerAuth}, types)
+	}
+	s := &Segment{Text: text}
+	s.SegmentType, s.Types = parseType(text)
+	a.True(s.IsMatchEvent())
+	a.True(s.Is(PlayerAuth))
+	// The screen names in a match are the players, not the auth
+	a.False(s.IsPlayerAuth())
+	a.False(s.IsMatchStart())
+}
+
+func TestParseTypeUnknown(t *testing.T) {
+	a := assert.New(t)
+	primary, types := parseType([]byte(`<== Log.Info(290)`))
+	a.Equal(Unknown, primary)
+	a.Empty(types)
+}
+
+func TestSegmentRulesOrdered(t *testing.T) {
+	for i := 1; i < len(segmentRules); i++ {
+		assert.True(t, segmentRules[i-1].Priority >= segmentRules[i].Priority)
+	}
+}
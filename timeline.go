@@ -0,0 +1,81 @@
+package gathering
+
+import (
+	"time"
+)
+
+// ArenaTurn is one turn of a game. Life totals are keyed by seat, taken from
+// the first and last game state of the turn. EnteredPlay has the cards that
+// came onto the battlefield during the turn, and Events everything the GRE
+// annotated, in order. Clock is the time each seat used during the turn.
+type ArenaTurn struct {
+	Number       int                    `json:"number"`
+	ActivePlayer int                    `json:"activePlayer"`
+	Start        *time.Time             `json:"start"`
+	Steps        []ArenaTurnStep        `json:"steps"`
+	LifeAtStart  map[int]int            `json:"lifeAtStart"`
+	LifeAtEnd    map[int]int            `json:"lifeAtEnd"`
+	EnteredPlay  []ArenaMatchGameObject `json:"enteredPlay"`
+	Events       []*GameEvent           `json:"events"`
+	Clock        map[int]*ArenaClock    `json:"clock"`
+}
+
+// ArenaTurnStep is a phase and step the turn went through, like
+// `Phase_Combat` and `Step_DeclareAttack`. Step is empty for phases without
+// steps.
+type ArenaTurnStep struct {
+	Phase string `json:"phase"`
+	Step  string `json:"step"`
+}
+
+func lifeTotals(g *GameSnapshot) map[int]int {
+	life := make(map[int]int)
+	for seat, p := range g.Players {
+		life[seat] = p.LifeTotal
+	}
+	return life
+}
+
+// recordTurn adds the change from prev to next to the game's turns. at is
+// when next was sent, if we know.
+func (g *ArenaGame) recordTurn(prev, next *GameSnapshot, at *time.Time) {
+	if next.TurnInfo == nil || next.Turn() == 0 {
+		return
+	}
+	var turn *ArenaTurn
+	if n := len(g.Turns); n > 0 && g.Turns[n-1].Number == next.Turn() {
+		turn = g.Turns[n-1]
+	} else {
+		turn = &ArenaTurn{
+			Number:       next.Turn(),
+			ActivePlayer: next.TurnInfo.ActivePlayer,
+			Start:        at,
+			LifeAtStart:  lifeTotals(next),
+		}
+		g.Turns = append(g.Turns, turn)
+	}
+	turn.LifeAtEnd = lifeTotals(next)
+	step := ArenaTurnStep{Phase: next.TurnInfo.Phase, Step: next.TurnInfo.Step}
+	if n := len(turn.Steps); step.Phase != "" && (n == 0 || turn.Steps[n-1] != step) {
+		turn.Steps = append(turn.Steps, step)
+	}
+	before := make(map[int]bool)
+	for _, o := range prev.Battlefield() {
+		before[o.InstanceID] = true
+	}
+	for _, o := range next.Battlefield() {
+		if !before[o.InstanceID] && o.Type == "GameObjectType_Card" {
+			turn.EnteredPlay = append(turn.EnteredPlay, o)
+		}
+	}
+}
+
+// recordEvents adds the events to the turn of the state they came with.
+// Events before the first turn, like drawing the opening hand, are left out.
+func (g *ArenaGame) recordEvents(events []*GameEvent, next *GameSnapshot) {
+	n := len(g.Turns)
+	if len(events) == 0 || n == 0 || g.Turns[n-1].Number != next.Turn() {
+		return
+	}
+	g.Turns[n-1].Events = append(g.Turns[n-1].Events, events...)
+}
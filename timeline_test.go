@@ -0,0 +1,68 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTimelineEvent = `{
+ "timestamp": "1554231701005",
+ "greToClientEvent": {"greToClientMessages": [
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Full", "gameStateId": 1,
+   "turnInfo": {"phase": "Phase_Beginning", "step": "Step_Upkeep", "turnNumber": 1, "activePlayer": 1},
+   "players": [{"lifeTotal": 20, "systemSeatNumber": 1}, {"lifeTotal": 20, "systemSeatNumber": 2}],
+   "zones": [{"zoneId": 28, "type": "ZoneType_Battlefield"}]}},
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Diff", "gameStateId": 2, "prevGameStateId": 1,
+   "turnInfo": {"phase": "Phase_Beginning", "step": "Step_Upkeep", "turnNumber": 1, "activePlayer": 1}}},
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Diff", "gameStateId": 3, "prevGameStateId": 2,
+   "turnInfo": {"phase": "Phase_Main1", "turnNumber": 1, "activePlayer": 1},
+   "zones": [{"zoneId": 28, "type": "ZoneType_Battlefield", "objectInstanceIds": [160, 161]}],
+   "gameObjects": [
+    {"instanceId": 160, "grpId": 68739, "type": "GameObjectType_Card", "zoneId": 28, "ownerSeatId": 1},
+    {"instanceId": 161, "grpId": 3, "type": "GameObjectType_Token", "zoneId": 28, "ownerSeatId": 1}
+   ]}},
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Diff", "gameStateId": 4, "prevGameStateId": 3,
+   "players": [{"lifeTotal": 18, "systemSeatNumber": 2}]}},
+  {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+   "type": "GameStateType_Diff", "gameStateId": 5, "prevGameStateId": 4,
+   "turnInfo": {"phase": "Phase_Beginning", "step": "Step_Draw", "turnNumber": 2, "activePlayer": 2}}}
+ ]}
+}`
+
+func TestGameTimeline(t *testing.T) {
+	a := assert.New(t)
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(testTimelineEvent), &event))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&event)
+	turns := match.Games[0].Turns
+	a.Len(turns, 2)
+
+	first := turns[0]
+	a.Equal(1, first.Number)
+	a.Equal(1, first.ActivePlayer)
+	a.Equal(int64(1554231701005), first.Start.UnixNano()/1e6)
+	a.Equal([]ArenaTurnStep{
+		{Phase: "Phase_Beginning", Step: "Step_Upkeep"},
+		{Phase: "Phase_Main1"},
+	}, first.Steps)
+	a.Equal(map[int]int{1: 20, 2: 20}, first.LifeAtStart)
+	a.Equal(map[int]int{1: 20, 2: 18}, first.LifeAtEnd)
+	a.Len(first.EnteredPlay, 1)
+	a.Equal(68739, first.EnteredPlay[0].GrpID)
+
+	second := turns[1]
+	a.Equal(2, second.ActivePlayer)
+	a.Equal(map[int]int{1: 20, 2: 18}, second.LifeAtStart)
+	a.Len(second.EnteredPlay, 0)
+
+	b, err := json.Marshal(match.Games[0])
+	a.Nil(err)
+	a.Contains(string(b), `"turns":[{"number":1,"activePlayer":1`)
+}
@@ -0,0 +1,126 @@
+package gathering
+
+// The GRE messages about the clock
+const (
+	GRETimerStateMessage = "GREMessageType_TimerStateMessage"
+	GRETimeoutMessage    = "GREMessageType_TimeoutMessage"
+)
+
+// The inactivity timer runs while a seat has a decision to make. The rope
+// shows once it gets within its warning threshold of running out.
+const timerInactivity = "TimerType_Inactivity"
+
+// resultReasonTimeout is the WinningReason of a game someone lost on time
+const resultReasonTimeout = "ResultReason_Timeout"
+
+// Timer is one of a seat's timers as the GRE last sent it
+type Timer struct {
+	TimerID             int    `json:"timerId"`
+	Type                string `json:"type"`
+	DurationSec         int    `json:"durationSec"`
+	ElapsedSec          int    `json:"elapsedSec"`
+	ElapsedMs           int    `json:"elapsedMs"`
+	Running             bool   `json:"running"`
+	Behavior            string `json:"behavior"`
+	WarningThresholdSec int    `json:"warningThresholdSec"`
+}
+
+// elapsed is the time on the timer in milliseconds
+func (t *Timer) elapsed() int {
+	if t.ElapsedMs > 0 {
+		return t.ElapsedMs
+	}
+	return t.ElapsedSec * 1000
+}
+
+// roped checks if the timer got close enough to running out to show the rope
+func (t *Timer) roped() bool {
+	if t.WarningThresholdSec <= 0 || t.DurationSec <= 0 {
+		return false
+	}
+	return t.elapsed() >= (t.DurationSec-t.WarningThresholdSec)*1000
+}
+
+// TimerStateMessage has the timers of a seat
+type TimerStateMessage struct {
+	SeatID int     `json:"seatId"`
+	Timers []Timer `json:"timers"`
+}
+
+// TimeoutMessage is sent when a seat's inactivity timer runs out and a
+// timeout is used
+type TimeoutMessage struct {
+	SeatID int `json:"seatId"`
+}
+
+// ArenaClock is how a seat used its time. DecisionMs is the time taken on
+// Decisions, the times the inactivity timer ran and stopped. Ropes are the
+// decisions that took long enough to show the rope, and Timeouts the
+// timeouts the seat used.
+type ArenaClock struct {
+	DecisionMs int `json:"decisionMs"`
+	Decisions  int `json:"decisions"`
+	Ropes      int `json:"ropes"`
+	Timeouts   int `json:"timeouts"`
+}
+
+// clocks are the clocks of the game and of the turn being played for a seat
+func (g *ArenaGame) clocks(seat int) []*ArenaClock {
+	if g.Clock == nil {
+		g.Clock = make(map[int]*ArenaClock)
+	}
+	if g.Clock[seat] == nil {
+		g.Clock[seat] = &ArenaClock{}
+	}
+	clocks := []*ArenaClock{g.Clock[seat]}
+	if n := len(g.Turns); n > 0 {
+		turn := g.Turns[n-1]
+		if turn.Clock == nil {
+			turn.Clock = make(map[int]*ArenaClock)
+		}
+		if turn.Clock[seat] == nil {
+			turn.Clock[seat] = &ArenaClock{}
+		}
+		clocks = append(clocks, turn.Clock[seat])
+	}
+	return clocks
+}
+
+// recordTimers counts a decision whenever a seat's inactivity timer stops
+func (g *ArenaGame) recordTimers(msg *TimerStateMessage) {
+	if g.timers == nil {
+		g.timers = make(map[int]Timer)
+	}
+	for _, t := range msg.Timers {
+		prev, ok := g.timers[t.TimerID]
+		g.timers[t.TimerID] = t
+		if t.Type != timerInactivity || t.Running || !ok || !prev.Running {
+			continue
+		}
+		for _, c := range g.clocks(msg.SeatID) {
+			c.Decisions++
+			c.DecisionMs += t.elapsed()
+			if t.roped() {
+				c.Ropes++
+			}
+		}
+	}
+}
+
+// recordTimeout counts a timeout for the seat in the message, or the seat it
+// was sent to when it doesn't say
+func (g *ArenaGame) recordTimeout(msg *GreToClientMessages) {
+	seat := 0
+	if msg.TimeoutMessage != nil {
+		seat = msg.TimeoutMessage.SeatID
+	}
+	if seat == 0 && len(msg.SystemSeatIDs) == 1 {
+		seat = msg.SystemSeatIDs[0]
+	}
+	if seat == 0 {
+		return
+	}
+	for _, c := range g.clocks(seat) {
+		c.Timeouts++
+	}
+}
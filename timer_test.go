@@ -0,0 +1,88 @@
+package gathering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTimerEvent = `{"greToClientEvent": {"greToClientMessages": [
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+  "type": "GameStateType_Full", "gameStateId": 1,
+  "turnInfo": {"turnNumber": 2, "activePlayer": 1}}},
+ {"type": "GREMessageType_TimerStateMessage", "systemSeatIds": [1], "timerStateMessage": {"seatId": 1, "timers": [
+  {"timerId": 5, "type": "TimerType_Inactivity", "durationSec": 150, "warningThresholdSec": 30, "running": true}]}},
+ {"type": "GREMessageType_TimerStateMessage", "systemSeatIds": [1], "timerStateMessage": {"seatId": 1, "timers": [
+  {"timerId": 5, "type": "TimerType_Inactivity", "durationSec": 150, "warningThresholdSec": 30, "elapsedMs": 4500}]}},
+ {"type": "GREMessageType_TimerStateMessage", "systemSeatIds": [1], "timerStateMessage": {"seatId": 1, "timers": [
+  {"timerId": 5, "type": "TimerType_Inactivity", "durationSec": 150, "warningThresholdSec": 30, "elapsedMs": 130000}]}},
+ {"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+  "type": "GameStateType_Diff", "gameStateId": 2, "prevGameStateId": 1,
+  "turnInfo": {"turnNumber": 3, "activePlayer": 2}}},
+ {"type": "GREMessageType_TimerStateMessage", "systemSeatIds": [1], "timerStateMessage": {"seatId": 1, "timers": [
+  {"timerId": 5, "type": "TimerType_Inactivity", "durationSec": 150, "warningThresholdSec": 30, "running": true}]}},
+ {"type": "GREMessageType_TimerStateMessage", "systemSeatIds": [1], "timerStateMessage": {"seatId": 1, "timers": [
+  {"timerId": 5, "type": "TimerType_Inactivity", "durationSec": 150, "warningThresholdSec": 30, "elapsedSec": 150}]}},
+ {"type": "GREMessageType_TimeoutMessage", "systemSeatIds": [1, 2], "timeoutMessage": {"seatId": 1}}
+]}}`
+
+func TestGameClock(t *testing.T) {
+	a := assert.New(t)
+	var event ArenaMatchEvent
+	a.Nil(json.Unmarshal([]byte(testTimerEvent), &event))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.LogMatchEvent(&event)
+	g := match.Games[0]
+	// A decision is counted when the timer stops, not again while it stays stopped
+	a.Equal(&ArenaClock{DecisionMs: 154500, Decisions: 2, Ropes: 1, Timeouts: 1}, g.Clock[1])
+	a.Nil(g.Clock[2])
+	a.Len(g.Turns, 2)
+	a.Equal(&ArenaClock{DecisionMs: 4500, Decisions: 1}, g.Turns[0].Clock[1])
+	a.Equal(&ArenaClock{DecisionMs: 150000, Decisions: 1, Ropes: 1, Timeouts: 1}, g.Turns[1].Clock[1])
+}
+
+func TestGameLostToTimeout(t *testing.T) {
+	a := assert.New(t)
+	var end ArenaGame
+	a.Nil(json.Unmarshal([]byte(`{"seatId": 1, "teamId": 1, "gameNumber": 1, "winningTeamId": 2,
+ "winningReason": "ResultReason_Timeout", "ropeShownCount": 3, "ropeExpiredCount": 2}`), &end))
+	match := &ArenaMatch{Games: []*ArenaGame{{}}}
+	match.UpdateGameEnd(&end)
+	g := match.Games[0]
+	a.Equal(ResultLoss, g.Result)
+	a.True(g.LostToTimeout)
+	a.Equal(3, *g.RopeShownCount)
+	a.Equal(2, *g.RopeExpiredCount)
+
+	end.WinningTeamID = end.TeamID
+	match.UpdateGameEnd(&end)
+	a.False(g.LostToTimeout)
+}
+
+func TestLogClockStandalone(t *testing.T) {
+	a := assert.New(t)
+	l := &bo3Log{}
+	l.segment(`<== Event.DeckSubmitV3(1)
+{"CourseDeck": {"id": "deck1", "mainDeck": [{"id": 1, "quantity": 4}]}}`)
+	l.segment(` (Incoming Event.MatchCreated)
+{"matchId": "m1", "opponentScreenName": "Opponent"}`)
+	l.segment(`{"greToClientEvent": {"greToClientMessages": [{"type": "GREMessageType_GameStateMessage", "gameStateMessage": {
+ "type": "GameStateType_Full", "gameStateId": 1, "turnInfo": {"turnNumber": 1, "activePlayer": 1}}}]}}`)
+	for _, running := range []string{"true", "false"} {
+		l.segment(`{"greToClientEvent": {"greToClientMessages": [{"type": "GREMessageType_TimerStateMessage", "systemSeatIds": [1],
+ "timerStateMessage": {"seatId": 1, "timers": [{"timerId": 5, "type": "TimerType_Inactivity", "durationSec": 150,
+  "warningThresholdSec": 30, "elapsedSec": 140, "running": ` + running + `}]}}]}}`)
+	}
+	l.segment(`{"greToClientEvent": {"greToClientMessages": [{"type": "GREMessageType_TimeoutMessage", "systemSeatIds": [1],
+ "timeoutMessage": {"seatId": 1}}]}}`)
+	p := NewLogParser(nil)
+	_, err := p.Parse(l)
+	a.Nil(err)
+	p.Flush()
+	x := p.Log().Extract()
+	a.Len(x.Matches, 1)
+	g := x.Matches[0].Games[0]
+	a.Equal(&ArenaClock{DecisionMs: 140000, Decisions: 1, Ropes: 1, Timeouts: 1}, g.Clock[1])
+	a.Equal(g.Clock[1], g.Turns[0].Clock[1])
+}
@@ -0,0 +1,171 @@
+package gathering
+
+import (
+	"reflect"
+	"sync"
+)
+
+// LiveMatch is what is happening in the match being played. Hand is the
+// player's hand, OpponentCards every card the opponent has shown this match.
+type LiveMatch struct {
+	MatchID       string                 `json:"matchId"`
+	EventID       string                 `json:"eventId"`
+	Opponent      string                 `json:"opponent"`
+	GameNumber    int                    `json:"gameNumber"`
+	Turn          int                    `json:"turn"`
+	ActivePlayer  int                    `json:"activePlayer"`
+	SeatID        int                    `json:"seatId"`
+	Life          map[int]int            `json:"life"`
+	Hand          []ArenaMatchGameObject `json:"hand"`
+	OpponentCards []OpponentCard         `json:"opponentCards"`
+	Wins          int                    `json:"wins"`
+	Losses        int                    `json:"losses"`
+	Result        Result                 `json:"result"`
+	Done          bool                   `json:"done"`
+}
+
+// Tracker follows the match being played as the log is written. Feed it the
+// segments the LogParser returns, and Subscribe to hear about changes. A
+// segment is only finished once the next one starts, so the tracker is a
+// segment behind Arena.
+// The matches are followed by an Extractor, which the tracker feeds the
+// segments to; use it for everything else found in the log, rather than
+// feeding the segments to an Extractor of your own.
+type Tracker struct {
+	mu          sync.Mutex
+	extractor   *Extractor
+	match       *ArenaMatch
+	live        *LiveMatch
+	subscribers []chan *LiveMatch
+}
+
+// NewTracker creates a Tracker that follows the matches of an Extractor. If
+// the extractor is nil, the tracker has one of its own.
+func NewTracker(x *Extractor) *Tracker {
+	if x == nil {
+		x = NewExtractor()
+	}
+	return &Tracker{
+		extractor: x,
+	}
+}
+
+// Extractor is the Extractor the tracker feeds
+func (t *Tracker) Extractor() *Extractor {
+	return t.extractor
+}
+
+// Reset forgets everything, the extractor too, for when the log starts over
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.extractor.Reset()
+	t.match = nil
+	t.update()
+}
+
+// Feed adds new segments to the extractor and tells subscribers if the match
+// changed
+func (t *Tracker) Feed(segments []*Segment) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range segments {
+		t.extractor.Feed([]*Segment{s})
+		if m := t.extractor.matches.match; m != nil {
+			t.match = m
+		}
+	}
+	t.update()
+}
+
+// Match is the match being played, or the last one played. It is nil until
+// a match starts. It keeps changing as segments are fed, so only read it
+// between calls to Feed.
+func (t *Tracker) Match() *ArenaMatch {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.match
+}
+
+// Live is the current state of the match, or nil before a match starts
+func (t *Tracker) Live() *LiveMatch {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.live
+}
+
+// Subscribe returns a channel that gets the LiveMatch whenever it changes.
+// Sending never blocks the tracker; a subscriber that falls behind only gets
+// the latest state. nil is sent when the tracker is Reset.
+func (t *Tracker) Subscribe() <-chan *LiveMatch {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ch := make(chan *LiveMatch, 1)
+	t.subscribers = append(t.subscribers, ch)
+	return ch
+}
+
+// Unsubscribe stops updates to a channel from Subscribe and closes it
+func (t *Tracker) Unsubscribe(ch <-chan *LiveMatch) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, sub := range t.subscribers {
+		if sub == ch {
+			t.subscribers = append(t.subscribers[:i], t.subscribers[i+1:]...)
+			close(sub)
+			return
+		}
+	}
+}
+
+// update works out the live state and sends it out if it changed
+func (t *Tracker) update() {
+	live := t.liveMatch()
+	if reflect.DeepEqual(live, t.live) {
+		return
+	}
+	t.live = live
+	for _, ch := range t.subscribers {
+		select {
+		case ch <- live:
+		default:
+			// Replace the update they haven't read yet
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- live
+		}
+	}
+}
+
+func (t *Tracker) liveMatch() *LiveMatch {
+	m := t.match
+	if m == nil || len(m.Games) == 0 {
+		return nil
+	}
+	game := m.Games[m.currentGame]
+	state := game.State().Current()
+	seat := game.PlayerSeat()
+	live := &LiveMatch{
+		MatchID:       m.MatchID,
+		EventID:       m.EventID,
+		Opponent:      m.OpponentScreenName,
+		GameNumber:    m.currentGame + 1,
+		Turn:          state.Turn(),
+		SeatID:        seat,
+		Life:          lifeTotals(state),
+		OpponentCards: m.InferOpponentDeck().Cards,
+		Wins:          m.Wins,
+		Losses:        m.Losses,
+		Result:        m.Result,
+		Done:          t.extractor.matches.match != m,
+	}
+	if state.TurnInfo != nil {
+		live.ActivePlayer = state.TurnInfo.ActivePlayer
+	}
+	if seat != 0 {
+		live.Hand = state.InZone("ZoneType_Hand", seat)
+	}
+	return live
+}
@@ -0,0 +1,83 @@
+package gathering
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackerFollowsMatch(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	_, err := p.Parse(newBo3Log(true))
+	a.Nil(err)
+	p.Flush()
+	segments := p.Log().Segments
+
+	tracker := NewTracker(nil)
+	updates := tracker.Subscribe()
+	var seen []*LiveMatch
+	for _, s := range segments {
+		tracker.Feed([]*Segment{s})
+		select {
+		case live := <-updates:
+			seen = append(seen, live)
+		default:
+		}
+	}
+	a.True(len(seen) > 3)
+	a.Nil(seen[0].Hand)
+	first := seen[0]
+	a.Equal("m1", first.MatchID)
+	a.Equal("Opponent", first.Opponent)
+	a.Equal(1, first.GameNumber)
+	a.False(first.Done)
+
+	games := make(map[int]bool)
+	for _, live := range seen {
+		games[live.GameNumber] = true
+	}
+	a.Equal(map[int]bool{1: true, 2: true, 3: true}, games)
+
+	last := seen[len(seen)-1]
+	a.Equal(last, tracker.Live())
+	a.True(last.Done)
+	a.Equal(ResultWin, last.Result)
+	a.Equal(2, last.Wins)
+	a.Equal(1, last.Losses)
+	a.Equal(tracker.Match().MatchID, "m1")
+	// The tracker follows the match its extractor has
+	matches := tracker.Extractor().Extraction().Matches
+	a.Len(matches, 1)
+	a.Equal(tracker.Match(), matches[0])
+
+	// Nothing changed, nothing is sent
+	tracker.Feed(nil)
+	select {
+	case <-updates:
+		a.Fail("unexpected update")
+	default:
+	}
+	tracker.Unsubscribe(updates)
+	_, ok := <-updates
+	a.False(ok)
+}
+
+func TestTrackerSlowSubscriber(t *testing.T) {
+	a := assert.New(t)
+	p := NewLogParser(nil)
+	_, err := p.Parse(newBo3Log(false))
+	a.Nil(err)
+	p.Flush()
+	tracker := NewTracker(nil)
+	updates := tracker.Subscribe()
+	// Every segment at once, one at a time, without reading in between
+	for _, s := range p.Log().Segments {
+		tracker.Feed([]*Segment{s})
+	}
+	live := <-updates
+	a.True(live.Done)
+	tracker.Reset()
+	a.Nil(tracker.Live())
+	a.Nil(<-updates)
+}